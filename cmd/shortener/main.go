@@ -3,10 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
 	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/geoip"
 	"github.com/LorezV/url-shorter.git/internal/handlers"
+	"github.com/LorezV/url-shorter.git/internal/healthcheck"
+	"github.com/LorezV/url-shorter.git/internal/jobs"
 	"github.com/LorezV/url-shorter.git/internal/middlewares"
+	"github.com/LorezV/url-shorter.git/internal/quota"
+	"github.com/LorezV/url-shorter.git/internal/ratelimit"
 	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/unfurl"
 	"log"
 	"math/rand"
 	"net"
@@ -33,6 +41,31 @@ func init() {
 		log.Fatal(err)
 	}
 
+	err = quota.LoadTeams(config.AppConfig.QuotaTeamsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = clientip.SetTrustedProxies(config.AppConfig.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(config.AppConfig.GeoIPDatabase) > 0 {
+		database, err := geoip.Open(config.AppConfig.GeoIPDatabase)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		geoip.GlobalLocator = database
+	}
+
+	screening.GlobalBlocklist = screening.MakeBlocklist(config.AppConfig.BlocklistFile)
+	err = screening.GlobalBlocklist.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	rand.Seed(time.Now().UnixNano())
 }
 
@@ -41,6 +74,19 @@ func main() {
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		for range sighup {
+			if err := screening.GlobalBlocklist.Load(); err != nil {
+				log.Printf("Can't reload blocklist: %v", err)
+			} else {
+				log.Printf("Blocklist reloaded, %d entries.", len(screening.GlobalBlocklist.Entries()))
+			}
+		}
+	}()
+
 	fmt.Println("Build version:", buildVersion)
 	fmt.Println("Build version:", buildDate)
 	fmt.Println("Build version:", buildCommit)
@@ -51,6 +97,38 @@ func main() {
 		repository2.GlobalRepository = repository2.MakeMemoryRepository()
 	}
 
+	switch config.AppConfig.RateLimitStorage {
+	case "postgres":
+		if config.DB == nil {
+			log.Fatal("Postgres rate limit storage requires database dsn.")
+		}
+
+		store, err := ratelimit.MakePostgresStore(config.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ratelimit.GlobalStore = store
+	case "memory":
+		ratelimit.GlobalStore = ratelimit.MakeMemoryStore()
+	default:
+		log.Fatalf("Unknown rate limit storage %s.", config.AppConfig.RateLimitStorage)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+
+	if config.AppConfig.UnfurlPages {
+		unfurl.GlobalFetcher = unfurl.MakeFetcher(config.AppConfig.UnfurlTimeout, config.AppConfig.UnfurlMaxBytes, config.AppConfig.UnfurlConcurrency, config.AppConfig.UnfurlQueueSize, false)
+		go unfurl.GlobalFetcher.Run(jobsCtx)
+	}
+
+	go jobs.RunPurge(jobsCtx, config.AppConfig.PurgeInterval, config.AppConfig.TrashGracePeriod)
+
+	if config.AppConfig.HealthCheckInterval > 0 {
+		checker := healthcheck.MakeChecker(config.AppConfig.HealthCheckTimeout, config.AppConfig.HealthCheckConcurrency, config.AppConfig.HealthCheckHostDelay, false)
+		go jobs.RunHealthCheck(jobsCtx, config.AppConfig.HealthCheckInterval, config.AppConfig.HealthCheckBatch, checker)
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -59,17 +137,63 @@ func main() {
 
 	r.Mount("/debug", middleware.Profiler())
 
+	limitCreate := middlewares.RateLimit("create", config.AppConfig.RateLimitCreate)
+	limitBatch := middlewares.RateLimit("batch", config.AppConfig.RateLimitBatch)
+	limitRedirect := middlewares.RateLimit("redirect", config.AppConfig.RateLimitRedirect)
+
 	r.Route("/", func(r chi.Router) {
-		r.Get("/{id}", handlers.GetURL)
-		r.Post("/", handlers.CreateURL)
+		r.With(limitRedirect).Get("/{id}", handlers.GetURL)
+		r.With(limitRedirect).Post("/{id}", handlers.UnlockURL)
+		r.With(limitRedirect).Post("/{id}/conversion", handlers.RecordConversion)
+		r.With(limitCreate).Post("/", handlers.CreateURL)
 	})
-	r.Post("/api/shorten/batch", handlers.BatchURLJson)
-	r.Post("/api/shorten", handlers.CreateURLJson)
+	r.With(limitBatch).Post("/api/shorten/batch", handlers.BatchURLJson)
+	r.With(limitBatch).Post("/api/shorten/batch/stream", handlers.BatchURLStream)
+	r.With(limitCreate).Post("/api/shorten", handlers.CreateURLJson)
 	r.Route("/api/user/urls", func(r chi.Router) {
 		r.Get("/", handlers.GetUserUrls)
 		r.Delete("/", handlers.DeleteUserUrls)
+		r.Get("/trash", handlers.GetUserTrash)
+		r.Get("/search", handlers.SearchUserUrls)
+		r.Get("/broken", handlers.GetUserBrokenUrls)
+		r.With(limitBatch).Post("/import", handlers.ImportUserUrls)
+		r.Get("/export", handlers.ExportUserUrls)
+		r.Post("/restore", handlers.RestoreUserUrls)
+		r.Get("/{id}", handlers.GetUserURL)
+		r.Patch("/{id}", handlers.PatchUserURL)
+		r.Get("/{id}/history", handlers.GetUserURLHistory)
+		r.Get("/{id}/stats", handlers.GetUserURLStats)
+		r.Get("/{id}/qr", handlers.GetUserURLQR)
+		r.Put("/{id}/tags", handlers.SetUserURLTags)
+		r.Put("/{id}/folder", handlers.SetUserURLFolder)
+	})
+	r.Route("/api/user/tags", func(r chi.Router) {
+		r.Get("/", handlers.GetUserTags)
+		r.Patch("/{tag}", handlers.RenameUserTag)
+		r.Delete("/{tag}", handlers.DeleteUserTag)
 	})
+	r.Route("/api/user/folders", func(r chi.Router) {
+		r.Get("/", handlers.GetUserFolders)
+		r.Post("/", handlers.CreateUserFolder)
+		r.Patch("/{folderID}", handlers.UpdateUserFolder)
+		r.Delete("/{folderID}", handlers.DeleteUserFolder)
+	})
+	r.Get("/api/user/quota", handlers.GetUserQuota)
 	r.Get("/ping", handlers.CheckPing)
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(middlewares.AdminAuthorization)
+		r.Get("/urls", handlers.AdminFindURL)
+		r.Get("/urls/{id}", handlers.AdminGetURL)
+		r.Post("/urls/{id}/disable", handlers.AdminDisableURL)
+		r.Post("/urls/{id}/enable", handlers.AdminEnableURL)
+		r.Get("/users/{userID}/urls", handlers.AdminGetUserUrls)
+		r.Delete("/users/{userID}/urls", handlers.AdminDeleteUserUrls)
+		r.Get("/audit", handlers.AdminGetAuditLog)
+		r.Get("/blocklist", handlers.AdminGetBlocklist)
+		r.Post("/blocklist", handlers.AdminAddBlocklistEntry)
+		r.Delete("/blocklist", handlers.AdminRemoveBlocklistEntry)
+		r.Post("/blocklist/reload", handlers.AdminReloadBlocklist)
+	})
 
 	srv := &http.Server{Handler: r}
 	ln, err := net.Listen("tcp", config.AppConfig.ServerAddress)
@@ -80,16 +204,31 @@ func main() {
 	go func() {
 		<-sigint
 
-		err := repository2.GlobalRepository.Close()
+		stopJobs()
+
+		// Server is stopped first, so stores are closed after the last request.
+		err := srv.Shutdown(context.Background())
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		err = srv.Shutdown(context.Background())
+		err = ratelimit.GlobalStore.Close()
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		err = repository2.GlobalRepository.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if geoip.GlobalLocator != nil {
+			err = geoip.GlobalLocator.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
 		close(shutdown)
 	}()
 
@@ -0,0 +1,97 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies contains networks of reverse proxies whose X-Forwarded-For and X-Real-IP headers are trusted.
+var TrustedProxies []*net.IPNet
+
+// SetTrustedProxies parses ip addresses and cidr networks of trusted proxies.
+func SetTrustedProxies(values []string) error {
+	networks := make([]*net.IPNet, 0, len(values))
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", value)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	TrustedProxies = networks
+
+	return nil
+}
+
+func isTrusted(ip net.IP) bool {
+	for _, network := range TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FromRequest returns ip address of client. When request comes from trusted proxy, X-Forwarded-For is walked
+// from the right skipping trusted proxies and X-Real-IP is used if it is missing, otherwise peer address is returned.
+func FromRequest(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		peer = host
+	}
+
+	ip := net.ParseIP(peer)
+	if ip == nil || !isTrusted(ip) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+		for index := len(hops) - 1; index >= 0; index-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[index]))
+			if hop == nil {
+				break
+			}
+
+			ip = hop
+			if !isTrusted(hop) {
+				return hop.String()
+			}
+		}
+
+		return ip.String()
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+
+	return peer
+}
@@ -0,0 +1,80 @@
+package clientip
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRequest(t *testing.T) {
+	require.NoError(t, SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}))
+	defer func() { TrustedProxies = nil }()
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "Direct client.",
+			remoteAddr: "203.0.113.7:51000",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Untrusted peer can't spoof headers.",
+			remoteAddr: "203.0.113.7:51000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Trusted proxy forwards client.",
+			remoteAddr: "10.1.2.3:443",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Chain of trusted proxies is skipped, spoofed left part is ignored.",
+			remoteAddr: "10.1.2.3:443",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 192.168.1.1, 10.0.0.5"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Trusted proxy with X-Real-IP.",
+			remoteAddr: "192.168.1.1:443",
+			headers:    map[string]string{"X-Real-IP": "2001:db8::1"},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "Malformed header stops at last valid hop.",
+			remoteAddr: "10.1.2.3:443",
+			headers:    map[string]string{"X-Forwarded-For": "garbage, 10.0.0.7"},
+			want:       "10.0.0.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+
+			r.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.want, FromRequest(r))
+		})
+	}
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	defer func() { TrustedProxies = nil }()
+
+	assert.Error(t, SetTrustedProxies([]string{"proxy.local"}))
+	assert.Error(t, SetTrustedProxies([]string{"10.0.0.0/33"}))
+	assert.NoError(t, SetTrustedProxies([]string{"::1", " 172.16.0.0/12 ", ""}))
+	assert.Len(t, TrustedProxies, 2)
+}
@@ -0,0 +1,76 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Locator resolves country of ip address.
+type Locator interface {
+	Country(ip net.IP) (string, error)
+	Close() error
+}
+
+// GlobalLocator it's locator variable used by redirects, it is nil when geo database isn't configured.
+var GlobalLocator Locator
+
+// Database is Locator which reads local MaxMind-format (.mmdb) file, lookups don't make network calls.
+type Database struct {
+	reader *maxminddb.Reader
+}
+
+// Open opens MaxMind-format database file by path, GeoLite2/GeoIP2 Country and City databases are supported.
+func Open(path string) (*Database, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Database{reader: reader}, nil
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Country returns ISO 3166-1 alpha-2 code of country of ip address, empty code means country is unknown.
+func (d *Database) Country(ip net.IP) (string, error) {
+	var record countryRecord
+
+	if err := d.reader.Lookup(ip, &record); err != nil {
+		return "", err
+	}
+
+	if len(record.Country.ISOCode) > 0 {
+		return record.Country.ISOCode, nil
+	}
+
+	return record.RegisteredCountry.ISOCode, nil
+}
+
+// Close unmaps database file.
+func (d *Database) Close() error {
+	return d.reader.Close()
+}
+
+// Lookup returns country code of ip address by GlobalLocator, empty code is returned when locator isn't configured,
+// address is invalid or isn't found.
+func Lookup(address string) string {
+	ip := net.ParseIP(address)
+	if GlobalLocator == nil || ip == nil {
+		return ""
+	}
+
+	country, err := GlobalLocator.Country(ip)
+	if err != nil {
+		return ""
+	}
+
+	return country
+}
@@ -0,0 +1,88 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestDatabase writes minimal IPv4 MaxMind database where 1.0.0.0/8 belongs to AU and other addresses are unknown.
+func writeTestDatabase(t *testing.T) string {
+	const nodeCount = 8
+
+	var tree []byte
+	record := func(value int) []byte { return []byte{byte(value >> 16), byte(value >> 8), byte(value)} }
+
+	for node := 0; node < nodeCount; node++ {
+		switch {
+		case node < nodeCount-1:
+			tree = append(tree, record(node+1)...)
+			tree = append(tree, record(nodeCount)...)
+		default:
+			tree = append(tree, record(nodeCount)...)
+			tree = append(tree, record(nodeCount+16)...)
+		}
+	}
+
+	str := func(value string) []byte { return append([]byte{0x40 | byte(len(value))}, value...) }
+
+	data := []byte{0xE1}
+	data = append(data, str("country")...)
+	data = append(data, 0xE1)
+	data = append(data, str("iso_code")...)
+	data = append(data, str("AU")...)
+
+	metadata := []byte("\xAB\xCD\xEFMaxMind.com")
+	metadata = append(metadata, 0xE6)
+	metadata = append(append(metadata, str("node_count")...), 0xC1, nodeCount)
+	metadata = append(append(metadata, str("record_size")...), 0xA1, 24)
+	metadata = append(append(metadata, str("ip_version")...), 0xA1, 4)
+	metadata = append(append(metadata, str("database_type")...), str("Test-Country")...)
+	metadata = append(append(metadata, str("binary_format_major_version")...), 0xA1, 2)
+	metadata = append(append(metadata, str("binary_format_minor_version")...), 0xA0)
+
+	file := append(append(append(tree, make([]byte, 16)...), data...), metadata...)
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	require.NoError(t, os.WriteFile(path, file, 0o600))
+
+	return path
+}
+
+func TestDatabase(t *testing.T) {
+	database, err := Open(writeTestDatabase(t))
+	require.NoError(t, err)
+	defer database.Close()
+
+	country, err := database.Country(net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "AU", country)
+
+	country, err = database.Country(net.ParseIP("8.8.8.8"))
+	require.NoError(t, err)
+	assert.Empty(t, country)
+}
+
+func TestLookup(t *testing.T) {
+	assert.Empty(t, Lookup("1.2.3.4"), "Lookup without locator.")
+
+	database, err := Open(writeTestDatabase(t))
+	require.NoError(t, err)
+
+	GlobalLocator = database
+	defer func() {
+		GlobalLocator.Close()
+		GlobalLocator = nil
+	}()
+
+	assert.Equal(t, "AU", Lookup("1.2.3.4"))
+	assert.Empty(t, Lookup("not an ip"))
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+}
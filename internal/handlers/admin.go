@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type adminURLResponse struct {
+	ID             string `json:"id"`
+	ShortURL       string `json:"short_url"`
+	OriginalURL    string `json:"original_url"`
+	UserID         string `json:"user_id"`
+	IsDeleted      bool   `json:"is_deleted"`
+	IsDisabled     bool   `json:"is_disabled"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	DisabledStatus int    `json:"disabled_status,omitempty"`
+}
+
+func makeAdminURLResponse(url repository.URL) adminURLResponse {
+	return adminURLResponse{
+		ID:             url.ID,
+		ShortURL:       url.Short,
+		OriginalURL:    url.Original,
+		UserID:         url.UserID,
+		IsDeleted:      url.IsDeleted,
+		IsDisabled:     url.IsDisabled,
+		DisabledReason: url.DisabledReason,
+		DisabledStatus: url.DisabledStatus,
+	}
+}
+
+// writeJSON marshals v and writes it to response with given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Can't marshal json.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// audit records admin action to repository audit log.
+func audit(r *http.Request, action, target, details string) {
+	actor, _ := r.Context().Value(utils.ContextKey("admin")).(string)
+	record := repository.AuditRecord{Actor: actor, Action: action, Target: target, Details: details}
+
+	if err := repository.GlobalRepository.InsertAuditRecord(r.Context(), record); err != nil {
+		log.Printf("Can't write audit record %s %s: %v", action, target, err)
+	}
+}
+
+// AdminGetURL handler returns any url by id for admin.
+func AdminGetURL(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	url, ok := repository.GlobalRepository.Get(r.Context(), id)
+	audit(r, "url.lookup", id, "")
+
+	if !ok {
+		http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, makeAdminURLResponse(url))
+}
+
+// AdminFindURL handler returns url by original url from "original" query parameter for admin. Parameter is normalized
+// like originals of created urls.
+func AdminFindURL(w http.ResponseWriter, r *http.Request) {
+	original := r.URL.Query().Get("original")
+	if len(original) == 0 {
+		http.Error(w, "The query parameter original is missing", http.StatusBadRequest)
+		return
+	}
+
+	original, err := urlnorm.Normalize(original)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url, ok := repository.GlobalRepository.GetByOriginal(r.Context(), original)
+	audit(r, "url.lookup", original, "")
+
+	if !ok {
+		http.Error(w, "URL with this original not found!", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, makeAdminURLResponse(url))
+}
+
+// AdminDisableURL handler disables url by id, GetURL then shows reason with 410 or 451 status.
+func AdminDisableURL(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var data struct {
+		Reason string `json:"reason"`
+		Status int    `json:"status"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(data.Reason) == 0 {
+		http.Error(w, "Can't disable url without reason.", http.StatusBadRequest)
+		return
+	}
+
+	if data.Status == 0 {
+		data.Status = http.StatusGone
+	}
+
+	if data.Status != http.StatusGone && data.Status != http.StatusUnavailableForLegalReasons {
+		http.Error(w, "Status must be 410 or 451.", http.StatusBadRequest)
+		return
+	}
+
+	url, err := repository.GlobalRepository.SetDisabled(r.Context(), id, true, data.Reason, data.Status)
+	if err != nil {
+		if errors.Is(err, repository.ErrorURLNotFound) {
+			http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	audit(r, "url.disable", id, fmt.Sprintf("status=%d reason=%s", data.Status, data.Reason))
+	writeJSON(w, http.StatusOK, makeAdminURLResponse(url))
+}
+
+// AdminEnableURL handler re-enables previously disabled url by id.
+func AdminEnableURL(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var data struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url, err := repository.GlobalRepository.SetDisabled(r.Context(), id, false, "", 0)
+	if err != nil {
+		if errors.Is(err, repository.ErrorURLNotFound) {
+			http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	audit(r, "url.enable", id, "reason="+data.Reason)
+	writeJSON(w, http.StatusOK, makeAdminURLResponse(url))
+}
+
+// AdminGetUserUrls handler returns all urls of user from path parameter for admin.
+func AdminGetUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	urls, _, err := repository.GlobalRepository.GetAllByUser(r.Context(), userID, repository.ListOptions{})
+	if err != nil {
+		http.Error(w, "Can't get urls from repository.", http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, "user.urls.list", userID, "")
+
+	v := make([]adminURLResponse, len(urls))
+	for index, url := range urls {
+		v[index] = makeAdminURLResponse(url)
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+// AdminDeleteUserUrls handler deletes all urls of user from path parameter.
+func AdminDeleteUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	count, err := repository.GlobalRepository.DeleteAllByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, "user.urls.delete", userID, fmt.Sprintf("deleted=%d", count))
+
+	writeJSON(w, http.StatusOK, struct {
+		Deleted int `json:"deleted"`
+	}{Deleted: count})
+}
+
+// AdminGetAuditLog handler returns last audit records, count is limited by "limit" query parameter.
+func AdminGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+
+	if value := r.URL.Query().Get("limit"); len(value) > 0 {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "The query parameter limit must be positive number", http.StatusBadRequest)
+			return
+		}
+
+		limit = parsed
+	}
+
+	records, err := repository.GlobalRepository.GetAuditRecords(r.Context(), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if records == nil {
+		records = []repository.AuditRecord{}
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// AdminGetBlocklist handler returns all blocklist entries.
+func AdminGetBlocklist(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, screening.GlobalBlocklist.Entries())
+}
+
+// AdminAddBlocklistEntry handler adds domain, regex or cidr entry to blocklist.
+func AdminAddBlocklistEntry(w http.ResponseWriter, r *http.Request) {
+	var entry screening.Entry
+
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, err := screening.GlobalBlocklist.Add(entry)
+	if errors.Is(err, screening.ErrorInvalidEntry) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !added {
+		http.Error(w, "Entry already exists.", http.StatusConflict)
+		return
+	}
+
+	audit(r, "blocklist.add", entry.Type+":"+entry.Value, "")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// AdminRemoveBlocklistEntry handler removes entry from blocklist.
+func AdminRemoveBlocklistEntry(w http.ResponseWriter, r *http.Request) {
+	var entry screening.Entry
+
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	removed, err := screening.GlobalBlocklist.Remove(entry)
+	if errors.Is(err, screening.ErrorInvalidEntry) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !removed {
+		http.Error(w, "Entry not found.", http.StatusNotFound)
+		return
+	}
+
+	audit(r, "blocklist.remove", entry.Type+":"+entry.Value, "")
+	w.WriteHeader(http.StatusOK)
+}
+
+// AdminReloadBlocklist handler reloads blocklist from file.
+func AdminReloadBlocklist(w http.ResponseWriter, r *http.Request) {
+	if err := screening.GlobalBlocklist.Load(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := screening.GlobalBlocklist.Entries()
+	audit(r, "blocklist.reload", "", fmt.Sprintf("entries=%d", len(entries)))
+
+	writeJSON(w, http.StatusOK, struct {
+		Entries int `json:"entries"`
+	}{Entries: len(entries)})
+}
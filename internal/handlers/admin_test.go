@@ -0,0 +1,232 @@
+package handlers_test
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/handlers"
+	"github.com/LorezV/url-shorter.git/internal/middlewares"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeAdminServer() *httptest.Server {
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Get("/{id}", handlers.GetURL)
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(middlewares.AdminAuthorization)
+		r.Get("/urls", handlers.AdminFindURL)
+		r.Get("/urls/{id}", handlers.AdminGetURL)
+		r.Post("/urls/{id}/disable", handlers.AdminDisableURL)
+		r.Post("/urls/{id}/enable", handlers.AdminEnableURL)
+		r.Get("/users/{userID}/urls", handlers.AdminGetUserUrls)
+		r.Delete("/users/{userID}/urls", handlers.AdminDeleteUserUrls)
+		r.Get("/audit", handlers.AdminGetAuditLog)
+	})
+
+	return httptest.NewServer(r)
+}
+
+func testAdminRequest(t *testing.T, ts *httptest.Server, method, path, token, body string) (http.Response, string) {
+	req, err := makeRequest(ts, method, path, strings.NewReader(body))
+	require.NoError(t, err)
+
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := makeClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return *resp, string(respBody)
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		token      string
+		statusCode int
+	}{
+		{
+			name:       "Admin API disabled without configured token.",
+			adminToken: "",
+			token:      "secret",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "Request with wrong token.",
+			adminToken: "secret",
+			token:      "wrong",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "Request with valid token.",
+			adminToken: "secret",
+			token:      "secret",
+			statusCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig.AdminToken = tt.adminToken
+			defer func() { config.AppConfig.AdminToken = "" }()
+
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+			ts := makeAdminServer()
+			defer ts.Close()
+
+			resp, _ := testAdminRequest(t, ts, http.MethodGet, "/api/admin/audit", tt.token, "")
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestAdminAuthorizationScheme(t *testing.T) {
+	config.AppConfig.AdminToken = "secret"
+	defer func() { config.AppConfig.AdminToken = "" }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	ts := makeAdminServer()
+	defer ts.Close()
+
+	for header, statusCode := range map[string]int{
+		"secret":        http.StatusUnauthorized,
+		"Basic secret":  http.StatusUnauthorized,
+		"Bearer ":       http.StatusUnauthorized,
+		"Bearer secre":  http.StatusUnauthorized,
+		"bearer secret": http.StatusOK,
+		"Bearer secret": http.StatusOK,
+	} {
+		req, err := makeRequest(ts, http.MethodGet, "/api/admin/audit", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", header)
+
+		resp, err := makeClient().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, statusCode, resp.StatusCode, header)
+		if statusCode == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAdminFindURLNormalized(t *testing.T) {
+	config.AppConfig.AdminToken = "secret"
+	config.AppConfig.URLStripDefaultPort = true
+	defer func() {
+		config.AppConfig.AdminToken = ""
+		config.AppConfig.URLStripDefaultPort = false
+	}()
+
+	original, err := urlnorm.Normalize("https://example.com/docs")
+	require.NoError(t, err)
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "docs01", Original: original, Short: "http://127.0.0.1:8080/docs01", UserID: "user01"})
+
+	ts := makeAdminServer()
+	defer ts.Close()
+
+	for _, query := range []string{"https://example.com/docs", "HTTPS://Example.COM/docs", "https://example.com:443/docs"} {
+		resp, body := testAdminRequest(t, ts, http.MethodGet, "/api/admin/urls?original="+url.QueryEscape(query), "secret", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, query)
+		assert.Contains(t, body, `"id":"docs01"`, query)
+	}
+
+	resp, _ := testAdminRequest(t, ts, http.MethodGet, "/api/admin/urls?original="+url.QueryEscape("not a url"), "secret", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAdminModeration(t *testing.T) {
+	config.AppConfig.AdminToken = "secret"
+	defer func() { config.AppConfig.AdminToken = "" }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "abuse1", Original: "https://phishing.example/", Short: "http://127.0.0.1:8080/abuse1", UserID: "user01"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "abuse2", Original: "https://phishing2.example/", Short: "http://127.0.0.1:8080/abuse2", UserID: "user01"})
+
+	ts := makeAdminServer()
+	defer ts.Close()
+
+	resp, body := testAdminRequest(t, ts, http.MethodGet, "/api/admin/urls?original=https://phishing.example", "secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"id":"abuse1"`)
+
+	resp, _ = testAdminRequest(t, ts, http.MethodPost, "/api/admin/urls/abuse1/disable", "secret", `{"reason":"Court order #42","status":451}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body = testRequest(t, ts, http.MethodGet, "/abuse1", nil)
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, resp.StatusCode)
+	assert.Contains(t, body, "Court order #42")
+
+	resp, _ = testAdminRequest(t, ts, http.MethodPost, "/api/admin/urls/abuse1/enable", "secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/abuse1", nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp, body = testAdminRequest(t, ts, http.MethodDelete, "/api/admin/users/user01/urls", "secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"deleted":2}`, body)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/abuse2", nil)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	resp, body = testAdminRequest(t, ts, http.MethodGet, "/api/admin/audit", "secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"action":"user.urls.delete"`)
+	assert.Contains(t, body, `"action":"url.disable"`)
+	assert.Contains(t, body, `"action":"url.enable"`)
+}
+
+func TestBlocklistScreening(t *testing.T) {
+	config.AppConfig.AdminToken = "secret"
+	defer func() {
+		config.AppConfig.AdminToken = ""
+		screening.GlobalBlocklist = screening.MakeBlocklist("")
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "phish1", Original: "https://login.evil.example/", Short: "http://127.0.0.1:8080/phish1"})
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Get("/{id}", handlers.GetURL)
+	r.Post("/", handlers.CreateURL)
+	r.With(middlewares.AdminAuthorization).Post("/api/admin/blocklist", handlers.AdminAddBlocklistEntry)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, _ := testRequest(t, ts, http.MethodGet, "/phish1", nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp, _ = testAdminRequest(t, ts, http.MethodPost, "/api/admin/blocklist", "secret", `{"type":"domain","value":"evil.example"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, body := testRequest(t, ts, http.MethodGet, "/phish1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+	assert.Contains(t, body, "may be harmful")
+
+	resp, _ = testRequest(t, ts, http.MethodPost, "/", strings.NewReader("https://www.evil.example/login"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
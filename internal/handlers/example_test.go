@@ -18,7 +18,7 @@ func ExampleGetURL() {
 }
 
 func ExampleCreateURL() {
-	r, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/", strings.NewReader("yandex.lyceum"))
+	r, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/", strings.NewReader("https://lyceum.yandex.ru"))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -27,7 +27,7 @@ func ExampleCreateURL() {
 }
 
 func ExampleCreateURLJson() {
-	r, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/api/shorten", strings.NewReader(`{"url": "yandex.lyceum"}`))
+	r, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/api/shorten", strings.NewReader(`{"url": "https://lyceum.yandex.ru"}`))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -44,11 +44,11 @@ func ExampleBatchURLJson() {
 	urls := []RequestElement{
 		{
 			CorrelationID: "SOMEID1",
-			OriginalURL:   "yandex.lyceum",
+			OriginalURL:   "https://lyceum.yandex.ru",
 		},
 		{
 			CorrelationID: "SOMEID1",
-			OriginalURL:   "google.com",
+			OriginalURL:   "https://google.com",
 		},
 	}
 
@@ -91,3 +91,23 @@ func ExampleCheckPing() {
 
 	fmt.Print(r)
 }
+
+func ExampleAdminDisableURL() {
+	r, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/api/admin/urls/URLID1/disable", strings.NewReader(`{"reason": "Phishing", "status": 410}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r.Header.Set("Authorization", "Bearer ADMIN_TOKEN")
+	fmt.Print(r)
+}
+
+func ExampleAdminGetAuditLog() {
+	r, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/api/admin/audit?limit=10", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r.Header.Set("Authorization", "Bearer ADMIN_TOKEN")
+	fmt.Print(r)
+}
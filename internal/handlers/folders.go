@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxFolderNameLength is limit of folder name length in characters.
+const maxFolderNameLength = 128
+
+// errorFolderCycle is error which returning when folder is moved into itself or its subfolder.
+var errorFolderCycle = errors.New("folder can't be moved into itself or its subfolder")
+
+// folderExists reports whether user has folder with id, zero id means root and always exists.
+func folderExists(ctx context.Context, userID string, id int64) (bool, error) {
+	if id == 0 {
+		return true, nil
+	}
+
+	_, err := repository.GlobalRepository.GetFolder(ctx, id, userID)
+	if errors.Is(err, repository.ErrorFolderNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// checkFolder writes 400 to response if user doesn't have folder with id.
+func checkFolder(w http.ResponseWriter, r *http.Request, userID string, id int64) bool {
+	exists, err := folderExists(r.Context(), userID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	if !exists {
+		http.Error(w, fmt.Sprintf("Folder %d not found.", id), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// checkFolderParent walks up from parent and returns errorFolderCycle if folder is one of its ancestors.
+func checkFolderParent(ctx context.Context, folder repository.Folder) error {
+	for parentID := folder.ParentID; parentID != 0; {
+		if parentID == folder.ID {
+			return errorFolderCycle
+		}
+
+		parent, err := repository.GlobalRepository.GetFolder(ctx, parentID, folder.UserID)
+		if err != nil {
+			return err
+		}
+
+		parentID = parent.ParentID
+	}
+
+	return nil
+}
+
+// folderParam returns folder id from path or writes 404 to response.
+func folderParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "folderID"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Folder with this id not found!", http.StatusNotFound)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// decodeFolder reads folder name and parent from body and validates them.
+func decodeFolder(w http.ResponseWriter, r *http.Request, folder *repository.Folder) bool {
+	var data struct {
+		Name     string `json:"name"`
+		ParentID int64  `json:"parent_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	folder.Name = strings.TrimSpace(data.Name)
+	folder.ParentID = data.ParentID
+
+	if len(folder.Name) == 0 || utf8.RuneCountInString(folder.Name) > maxFolderNameLength {
+		http.Error(w, fmt.Sprintf("Folder name must be from 1 to %d characters.", maxFolderNameLength), http.StatusBadRequest)
+		return false
+	}
+
+	return checkFolder(w, r, folder.UserID, folder.ParentID)
+}
+
+// GetUserFolders handler returns all user's folders, tree is described by parent_id of each folder.
+func GetUserFolders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	folders, err := repository.GlobalRepository.GetFoldersByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, folders)
+}
+
+// CreateUserFolder handler creates user's folder inside of parent folder or root.
+func CreateUserFolder(w http.ResponseWriter, r *http.Request) {
+	folder := repository.Folder{UserID: r.Context().Value(utils.ContextKey("userID")).(string)}
+
+	if !decodeFolder(w, r, &folder) {
+		return
+	}
+
+	folder, err := repository.GlobalRepository.InsertFolder(r.Context(), folder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, folder)
+}
+
+// UpdateUserFolder handler renames user's folder or moves it to another parent.
+func UpdateUserFolder(w http.ResponseWriter, r *http.Request) {
+	id, ok := folderParam(w, r)
+	if !ok {
+		return
+	}
+
+	folder := repository.Folder{ID: id, UserID: r.Context().Value(utils.ContextKey("userID")).(string)}
+
+	if !decodeFolder(w, r, &folder) {
+		return
+	}
+
+	if err := checkFolderParent(r.Context(), folder); err != nil {
+		if errors.Is(err, errorFolderCycle) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	folder, err := repository.GlobalRepository.UpdateFolder(r.Context(), folder)
+	if err != nil {
+		writeFolderError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, folder)
+}
+
+// DeleteUserFolder handler deletes user's folder, its subfolders and urls are moved to parent folder.
+func DeleteUserFolder(w http.ResponseWriter, r *http.Request) {
+	id, ok := folderParam(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	if err := repository.GlobalRepository.DeleteFolder(r.Context(), id, userID); err != nil {
+		writeFolderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeFolderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrorFolderNotFound) {
+		http.Error(w, "Folder with this id not found!", http.StatusNotFound)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SetUserURLFolder handler moves user's url to folder from "folder_id" field of body, zero moves it to root.
+func SetUserURLFolder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	var data struct {
+		FolderID int64 `json:"folder_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !checkFolder(w, r, userID, data.FolderID) {
+		return
+	}
+
+	url, err := repository.GlobalRepository.SetFolder(r.Context(), chi.URLParam(r, "id"), userID, data.FolderID)
+	writeUserURLResult(w, url, err)
+}
@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFolders(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "filed1", Original: "https://example.com/1", Short: "http://127.0.0.1:8080/filed1", UserID: "owner0000001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	var marketing, campaigns repository2.Folder
+
+	resp, body := testUserRequest(t, ts, http.MethodPost, "/api/user/folders", "owner0000001", `{"name":"Marketing"}`, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &marketing))
+
+	resp, body = testUserRequest(t, ts, http.MethodPost, "/api/user/folders", "owner0000001", fmt.Sprintf(`{"name":"Campaigns","parent_id":%d}`, marketing.ID), nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &campaigns))
+	assert.Equal(t, marketing.ID, campaigns.ParentID)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/user/folders", "stranger0001", fmt.Sprintf(`{"name":"Stolen","parent_id":%d}`, marketing.ID), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/user/folders/%d", marketing.ID), "owner0000001", fmt.Sprintf(`{"name":"Marketing","parent_id":%d}`, campaigns.ID), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodPut, "/api/user/urls/filed1/folder", "owner0000001", fmt.Sprintf(`{"folder_id":%d}`, campaigns.ID), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, fmt.Sprintf(`"folder_id":%d`, campaigns.ID))
+
+	resp, body = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", fmt.Sprintf(`{"url":"https://example.com/2","folder_id":%d}`, marketing.ID), nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://example.com/3","folder_id":999}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/user/urls?folder_id=%d", campaigns.ID), "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "filed1")
+	assert.NotContains(t, body, "example.com/2")
+
+	resp, _ = testUserRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/user/folders/%d", campaigns.ID), "owner0000001", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/user/urls?folder_id=%d", marketing.ID), "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "filed1")
+	assert.Contains(t, body, "example.com/2")
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/folders", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"name":"Marketing"`)
+	assert.NotContains(t, body, `"name":"Campaigns"`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/user/folders/%d", campaigns.ID), "owner0000001", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"strings"
+)
+
+// maxGeoRules is limit of geo rules of one url.
+const maxGeoRules = 50
+
+// isCountryCode reports whether value looks like ISO 3166-1 alpha-2 code.
+func isCountryCode(value string) bool {
+	return len(value) == 2 && 'A' <= value[0] && value[0] <= 'Z' && 'A' <= value[1] && value[1] <= 'Z'
+}
+
+// normalizeGeoRules checks url geo rules, uppercases country codes and normalizes destinations.
+func normalizeGeoRules(rules []repository.GeoRule) ([]repository.GeoRule, error) {
+	if len(rules) > maxGeoRules {
+		return nil, fmt.Errorf("url can have at most %d geo rules", maxGeoRules)
+	}
+
+	normalized := make([]repository.GeoRule, 0, len(rules))
+
+	for index, rule := range rules {
+		if len(rule.Countries) == 0 {
+			return nil, fmt.Errorf("geo rule %d: countries are required", index)
+		}
+
+		countries := make([]string, len(rule.Countries))
+		for position, country := range rule.Countries {
+			countries[position] = strings.ToUpper(strings.TrimSpace(country))
+			if !isCountryCode(countries[position]) {
+				return nil, fmt.Errorf("geo rule %d: %q is not ISO 3166-1 alpha-2 country code", index, country)
+			}
+		}
+
+		destination, err := normalizeDestination(fmt.Sprintf("geo rule %d destination", index), rule.Destination)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized = append(normalized, repository.GeoRule{Countries: countries, Destination: destination})
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	return normalized, nil
+}
+
+// normalizeGeoDefault checks destination of clients whose country matches no geo rule, empty value means url original.
+func normalizeGeoDefault(raw string) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	return normalizeDestination("geo_default", raw)
+}
@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
+	"github.com/LorezV/url-shorter.git/internal/geoip"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLocator map[string]string
+
+func (l fakeLocator) Country(ip net.IP) (string, error) {
+	return l[ip.String()], nil
+}
+
+func (l fakeLocator) Close() error {
+	return nil
+}
+
+func TestGeoRules(t *testing.T) {
+	require.NoError(t, clientip.SetTrustedProxies([]string{"127.0.0.1"}))
+	geoip.GlobalLocator = fakeLocator{"81.2.69.142": "GB", "2.16.6.1": "DE", "5.8.16.1": "RU"}
+	defer func() {
+		clientip.TrustedProxies = nil
+		geoip.GlobalLocator = nil
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/shop","geo_rules":[{"countries":["Great Britain"],"destination":"https://example.co.uk/shop"}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://example.com/shop","geo_rules":[
+		{"countries":["gb","ie"],"destination":"https://example.co.uk/shop"},
+		{"countries":["DE","AT","CH"],"destination":"https://example.de/shop"}
+	],"geo_default":"https://example.com/shop/international"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/shop")
+	require.True(t, ok)
+	assert.Equal(t, []string{"GB", "IE"}, url.GeoRules[0].Countries)
+
+	tests := []struct {
+		name      string
+		forwarded string
+		location  string
+	}{
+		{name: "British client.", forwarded: "81.2.69.142", location: "https://example.co.uk/shop"},
+		{name: "German client behind proxy chain.", forwarded: "2.16.6.1, 127.0.0.1", location: "https://example.de/shop"},
+		{name: "Client from country without rule gets default.", forwarded: "5.8.16.1", location: "https://example.com/shop/international"},
+		{name: "Unknown client gets default.", forwarded: "192.0.2.1", location: "https://example.com/shop/international"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", map[string]string{"X-Forwarded-For": tt.forwarded})
+
+			assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+			assert.Equal(t, tt.location, resp.Header.Get("Location"))
+		})
+	}
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/"+url.ID+"/stats", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var stats struct {
+		Clicks    int64                      `json:"clicks"`
+		Countries []repository2.CountryStats `json:"countries"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &stats))
+
+	assert.Equal(t, int64(4), stats.Clicks)
+	assert.ElementsMatch(t, []repository2.CountryStats{{Country: "", Clicks: 1}, {Country: "DE", Clicks: 1}, {Country: "GB", Clicks: 1}, {Country: "RU", Clicks: 1}}, stats.Countries)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"geo_default":""}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", map[string]string{"X-Forwarded-For": "5.8.16.1"})
+	assert.Equal(t, "https://example.com/shop", resp.Header.Get("Location"))
+}
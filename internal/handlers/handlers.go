@@ -4,11 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
 	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/geoip"
+	"github.com/LorezV/url-shorter.git/internal/quota"
 	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"github.com/LorezV/url-shorter.git/internal/useragent"
 	"github.com/LorezV/url-shorter.git/internal/utils"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -28,25 +37,51 @@ func CreateURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	original, err := urlnorm.Normalize(string(b))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectType, expiresAt, err := parseLinkOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(original); blocked {
+		http.Error(w, screening.ErrorBlocked.Error(), http.StatusForbidden)
+		return
+	}
+
 	userID := r.Context().Value(utils.ContextKey("userID")).(string)
-	url, err := repository.MakeURL(string(b), userID)
+
+	url, err := makeURL(r, original, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	url.RedirectType = redirectType
+	url.ExpiresAt = expiresAt
+
 	var status = http.StatusCreated
 
-	savedURL, err := repository.GlobalRepository.Insert(r.Context(), url)
+	savedURL, err := repository.GlobalRepository.Insert(r.Context(), url, quota.Limits(userID)...)
 	if err != nil {
 		if errors.Is(err, repository.ErrorURLDuplicate) {
 			status = http.StatusConflict
+		} else if errors.Is(err, quota.ErrorQuotaExceeded) {
+			http.Error(w, "Links quota exceeded.", http.StatusForbidden)
+			return
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	fetchPages(savedURL)
+
 	w.WriteHeader(status)
 	w.Write([]byte(savedURL.Short))
 }
@@ -66,7 +101,25 @@ func CreateURLJson(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var data struct {
-		URL string `json:"url"`
+		URL              string                   `json:"url"`
+		RedirectType     int                      `json:"redirect_type"`
+		ExpiresAt        *time.Time               `json:"expires_at"`
+		Title            string                   `json:"title"`
+		Description      string                   `json:"description"`
+		Tags             []string                 `json:"tags"`
+		FolderID         int64                    `json:"folder_id"`
+		Password         string                   `json:"password"`
+		MaxClicks        int64                    `json:"max_clicks"`
+		NotBefore        *time.Time               `json:"not_before"`
+		NotAfter         *time.Time               `json:"not_after"`
+		FallbackURL      string                   `json:"fallback_url"`
+		Rules            []repository.RoutingRule `json:"rules"`
+		Variants         []repository.Variant     `json:"variants"`
+		GeoRules         []repository.GeoRule     `json:"geo_rules"`
+		GeoDefault       string                   `json:"geo_default"`
+		QueryPassthrough *bool                    `json:"query_passthrough"`
+		QueryConflict    string                   `json:"query_conflict"`
+		UTM              map[string]string        `json:"utm"`
 	}
 
 	err = json.Unmarshal(b, &data)
@@ -81,25 +134,137 @@ func CreateURLJson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	original, err := urlnorm.Normalize(data.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateLinkOptions(data.RedirectType, data.ExpiresAt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateMetadata(data.Title, data.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateMaxClicks(data.MaxClicks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateSchedule(data.NotBefore, data.NotAfter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fallbackURL, err := normalizeFallbackURL(data.FallbackURL)
+	if err != nil {
+		http.Error(w, err.Error(), destinationErrorStatus(err))
+		return
+	}
+
+	rules, err := normalizeRules(data.Rules)
+	if err != nil {
+		http.Error(w, err.Error(), destinationErrorStatus(err))
+		return
+	}
+
+	variants, err := normalizeVariants(data.Variants)
+	if err != nil {
+		http.Error(w, err.Error(), destinationErrorStatus(err))
+		return
+	}
+
+	geoRules, err := normalizeGeoRules(data.GeoRules)
+	if err != nil {
+		http.Error(w, err.Error(), destinationErrorStatus(err))
+		return
+	}
+
+	geoDefault, err := normalizeGeoDefault(data.GeoDefault)
+	if err != nil {
+		http.Error(w, err.Error(), destinationErrorStatus(err))
+		return
+	}
+
+	if err := validateQueryConflict(data.QueryConflict); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utm, err := normalizeUTM(data.UTM)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := normalizeTags(data.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	passwordHash, err := hashPassword(data.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(original); blocked {
+		http.Error(w, screening.ErrorBlocked.Error(), http.StatusForbidden)
+		return
+	}
+
 	userID := r.Context().Value(utils.ContextKey("userID")).(string)
-	url, err := repository.MakeURL(data.URL, userID)
+	if !checkFolder(w, r, userID, data.FolderID) {
+		return
+	}
+
+	url, err := makeURL(r, original, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	url.RedirectType = data.RedirectType
+	url.ExpiresAt = data.ExpiresAt
+	url.Title = data.Title
+	url.Description = data.Description
+	url.Tags = tags
+	url.FolderID = data.FolderID
+	url.PasswordHash = passwordHash
+	url.MaxClicks = data.MaxClicks
+	url.NotBefore = data.NotBefore
+	url.NotAfter = data.NotAfter
+	url.FallbackURL = fallbackURL
+	url.Rules = rules
+	url.Variants = variants
+	url.GeoRules = geoRules
+	url.GeoDefault = geoDefault
+	url.QueryPassthrough = data.QueryPassthrough
+	url.QueryConflict = data.QueryConflict
+	url.UTM = utm
+
 	var status = http.StatusCreated
 
-	savedURL, err := repository.GlobalRepository.Insert(r.Context(), url)
+	savedURL, err := repository.GlobalRepository.Insert(r.Context(), url, quota.Limits(userID)...)
 	if err != nil {
 		if errors.Is(err, repository.ErrorURLDuplicate) {
 			status = http.StatusConflict
+		} else if errors.Is(err, quota.ErrorQuotaExceeded) {
+			http.Error(w, "Links quota exceeded.", http.StatusForbidden)
+			return
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	fetchPages(savedURL)
+
 	type responseData struct {
 		Result string `json:"result"`
 	}
@@ -117,8 +282,9 @@ func CreateURLJson(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetURL handler takes id argument from get request parameters and return url from database.
+// Id with + suffix or preview query parameter renders preview page of link instead of redirect.
 func GetURL(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	id, preview := previewID(r, chi.URLParam(r, "id"))
 
 	if id == "" {
 		http.Error(w, "The query parameter ID is missing", http.StatusBadRequest)
@@ -131,22 +297,197 @@ func GetURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if url.IsDeleted {
+	now := time.Now()
+
+	if url.IsDeleted || isExpired(url, now) || isExhausted(url) {
 		w.WriteHeader(http.StatusGone)
 		return
 	}
 
-	w.Header().Set("Location", url.Original)
-	w.WriteHeader(307)
+	if url.IsDisabled {
+		renderPage(w, url.DisabledStatus, disabledTemplate, url.DisabledReason)
+		return
+	}
+
+	if preview {
+		renderPreview(w, url, now)
+		return
+	}
+
+	// Password gate goes first, pages below may show destination of link.
+	if len(url.PasswordHash) > 0 && !isUnlocked(r, url) {
+		renderUnlockPage(w, http.StatusOK, url, "")
+		return
+	}
+
+	country := geoip.Lookup(clientip.FromRequest(r))
+	destination, routed := url.Route(useragent.Parse(r.UserAgent()))
+	if !routed {
+		destination, routed = url.RouteGeo(country)
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(destination); blocked {
+		renderPage(w, http.StatusOK, blockedTemplate, destination)
+		return
+	}
+
+	if state := scheduleState(url, now); state != scheduleActive {
+		writeOutsideSchedule(w, r, url, state, now)
+		return
+	}
+
+	// Variant is assigned only for visitors who pass all gates, so cookie doesn't pin assignment before.
+	variant := -1
+
+	if !routed && len(url.Variants) > 0 {
+		variant = assignVariant(r, url)
+		destination = url.Variants[variant].Destination
+
+		if _, blocked := screening.GlobalBlocklist.Check(destination); blocked {
+			renderPage(w, http.StatusOK, blockedTemplate, destination)
+			return
+		}
+
+		setVariantCookie(w, url, variant)
+	}
+
+	if err := repository.GlobalRepository.IncrementClicks(r.Context(), url.ID); err != nil {
+		if errors.Is(err, repository.ErrorURLExhausted) {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		log.Printf("Can't count click of %s: %v", url.ID, err)
+	}
+
+	if variant >= 0 {
+		if err := repository.GlobalRepository.RecordVariantEvent(r.Context(), url.ID, variant, repository.VariantClick); err != nil {
+			log.Printf("Can't count click of %s variant %d: %v", url.ID, variant, err)
+		}
+	}
+
+	if geoip.GlobalLocator != nil {
+		if err := repository.GlobalRepository.RecordCountryClick(r.Context(), url.ID, country); err != nil {
+			log.Printf("Can't count click of %s from %q: %v", url.ID, country, err)
+		}
+	}
+
+	writeRedirect(w, url, withQuery(r, url, destination))
 }
 
-// GetUserUrls handler takes userID from context and return all user's urls.
+// parseListOptions reads page, order and filters of user's urls listing from query parameters.
+func parseListOptions(r *http.Request) (repository.ListOptions, error) {
+	query := r.URL.Query()
+	options := repository.ListOptions{
+		Limit:      100,
+		Cursor:     query.Get("cursor"),
+		SortBy:     repository.SortByCreatedAt,
+		Descending: true,
+		Domain:     query.Get("domain"),
+		Search:     query.Get("q"),
+	}
+
+	if value := query.Get("tag"); len(value) > 0 {
+		tag, err := normalizeTag(value)
+		if err != nil {
+			return options, err
+		}
+
+		options.Tag = tag
+	}
+
+	if value := query.Get("folder_id"); len(value) > 0 {
+		folderID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || folderID < 0 {
+			return options, errors.New("the query parameter folder_id must be non-negative number")
+		}
+
+		options.FolderID = &folderID
+	}
+
+	if value := query.Get("broken"); len(value) > 0 {
+		broken, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, errors.New("the query parameter broken must be true or false")
+		}
+
+		options.Broken = broken
+	}
+
+	if value := query.Get("limit"); len(value) > 0 {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return options, errors.New("the query parameter limit must be number from 1 to 1000")
+		}
+
+		options.Limit = limit
+	}
+
+	switch value := query.Get("sort"); value {
+	case "", repository.SortByCreatedAt:
+	case repository.SortByClicks:
+		options.SortBy = value
+	default:
+		return options, errors.New("the query parameter sort must be created_at or clicks")
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		options.Descending = false
+	default:
+		return options, errors.New("the query parameter order must be asc or desc")
+	}
+
+	for name, target := range map[string]**time.Time{"created_after": &options.CreatedAfter, "created_before": &options.CreatedBefore} {
+		if value := query.Get(name); len(value) > 0 {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return options, fmt.Errorf("the query parameter %s must be RFC 3339 time", name)
+			}
+
+			*target = &parsed
+		}
+	}
+
+	return options, nil
+}
+
+// GetUserUrls handler takes userID from context and return page of user's urls.
+// Link header points to next page when there are more urls.
 func GetUserUrls(w http.ResponseWriter, r *http.Request) {
+	options, err := parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeUserUrls(w, r, options)
+}
+
+// GetUserBrokenUrls handler returns page of user's urls which destinations were found broken by health checker.
+func GetUserBrokenUrls(w http.ResponseWriter, r *http.Request) {
+	options, err := parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options.Broken = true
+	writeUserUrls(w, r, options)
+}
+
+// writeUserUrls writes page of user's urls selected by options.
+func writeUserUrls(w http.ResponseWriter, r *http.Request, options repository.ListOptions) {
 	userID := r.Context().Value(utils.ContextKey("userID")).(string)
-	b, err := repository.GlobalRepository.GetAllByUser(r.Context(), userID)
 
+	b, next, err := repository.GlobalRepository.GetAllByUser(r.Context(), userID, options)
 	if err != nil {
-		http.Error(w, "Can't get urls from repository.", http.StatusInternalServerError)
+		if errors.Is(err, repository.ErrorInvalidCursor) {
+			http.Error(w, "The query parameter cursor is invalid", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Can't get urls from repository.", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -155,14 +496,22 @@ func GetUserUrls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(next) > 0 {
+		query := r.URL.Query()
+		query.Set("cursor", next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, config.AppConfig.BaseURL, r.URL.Path, query.Encode()))
+	}
+
 	type responseElement struct {
 		ShortURL    string `json:"short_url"`
 		OriginalURL string `json:"original_url"`
+		Clicks      int64  `json:"clicks"`
+		urlMetadata
 	}
 	v := make([]responseElement, len(b))
 
 	for index, url := range b {
-		v[index] = responseElement{OriginalURL: url.Original, ShortURL: url.Short}
+		v[index] = responseElement{OriginalURL: url.Original, ShortURL: url.Short, Clicks: url.Clicks, urlMetadata: makeURLMetadata(url)}
 	}
 
 	j, err := json.Marshal(v)
@@ -191,6 +540,135 @@ func CheckPing(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// batchElement is one url of batch shortening request.
+type batchElement struct {
+	CorrelationID    string                   `json:"correlation_id"`
+	OriginalURL      string                   `json:"original_url"`
+	RedirectType     int                      `json:"redirect_type"`
+	ExpiresAt        *time.Time               `json:"expires_at"`
+	Title            string                   `json:"title"`
+	Description      string                   `json:"description"`
+	Tags             []string                 `json:"tags"`
+	FolderID         int64                    `json:"folder_id"`
+	Password         string                   `json:"password"`
+	MaxClicks        int64                    `json:"max_clicks"`
+	NotBefore        *time.Time               `json:"not_before"`
+	NotAfter         *time.Time               `json:"not_after"`
+	FallbackURL      string                   `json:"fallback_url"`
+	Rules            []repository.RoutingRule `json:"rules"`
+	Variants         []repository.Variant     `json:"variants"`
+	GeoRules         []repository.GeoRule     `json:"geo_rules"`
+	GeoDefault       string                   `json:"geo_default"`
+	QueryPassthrough *bool                    `json:"query_passthrough"`
+	QueryConflict    string                   `json:"query_conflict"`
+	UTM              map[string]string        `json:"utm"`
+}
+
+// makeBatchURL validates batch element and makes url from it. Invalid is an error of the element itself, err is an
+// internal error which fails the whole request.
+func makeBatchURL(r *http.Request, userID string, element batchElement) (url repository.URL, invalid error, err error) {
+	original, invalid := urlnorm.Normalize(element.OriginalURL)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	if invalid = validateLinkOptions(element.RedirectType, element.ExpiresAt); invalid != nil {
+		return url, invalid, nil
+	}
+
+	if invalid = validateMetadata(element.Title, element.Description); invalid != nil {
+		return url, invalid, nil
+	}
+
+	if invalid = validateMaxClicks(element.MaxClicks); invalid != nil {
+		return url, invalid, nil
+	}
+
+	if invalid = validateSchedule(element.NotBefore, element.NotAfter); invalid != nil {
+		return url, invalid, nil
+	}
+
+	fallbackURL, invalid := normalizeFallbackURL(element.FallbackURL)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	rules, invalid := normalizeRules(element.Rules)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	variants, invalid := normalizeVariants(element.Variants)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	geoRules, invalid := normalizeGeoRules(element.GeoRules)
+	if invalid == nil {
+		element.GeoDefault, invalid = normalizeGeoDefault(element.GeoDefault)
+	}
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	utm, invalid := normalizeUTM(element.UTM)
+	if invalid == nil {
+		invalid = validateQueryConflict(element.QueryConflict)
+	}
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	tags, invalid := normalizeTags(element.Tags)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	passwordHash, invalid := hashPassword(element.Password)
+	if invalid != nil {
+		return url, invalid, nil
+	}
+
+	exists, err := folderExists(r.Context(), userID, element.FolderID)
+	if err != nil {
+		return url, nil, err
+	}
+
+	if !exists {
+		return url, repository.ErrorFolderNotFound, nil
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(original); blocked {
+		return url, screening.ErrorBlocked, nil
+	}
+
+	url, err = makeURL(r, original, userID)
+	if err != nil {
+		return url, nil, err
+	}
+
+	url.RedirectType = element.RedirectType
+	url.ExpiresAt = element.ExpiresAt
+	url.Title = element.Title
+	url.Description = element.Description
+	url.Tags = tags
+	url.FolderID = element.FolderID
+	url.PasswordHash = passwordHash
+	url.MaxClicks = element.MaxClicks
+	url.NotBefore = element.NotBefore
+	url.NotAfter = element.NotAfter
+	url.FallbackURL = fallbackURL
+	url.Rules = rules
+	url.Variants = variants
+	url.GeoRules = geoRules
+	url.GeoDefault = element.GeoDefault
+	url.QueryPassthrough = element.QueryPassthrough
+	url.QueryConflict = element.QueryConflict
+	url.UTM = utm
+
+	return url, nil, nil
+}
+
 // BatchURLJson handler creates many urls in database in one request.
 func BatchURLJson(w http.ResponseWriter, r *http.Request) {
 	b, err := io.ReadAll(r.Body)
@@ -200,10 +678,7 @@ func BatchURLJson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var requestData []struct {
-		CorrelationID string `json:"correlation_id"`
-		OriginalURL   string `json:"original_url"`
-	}
+	var requestData []batchElement
 
 	err = json.Unmarshal(b, &requestData)
 
@@ -219,36 +694,69 @@ func BatchURLJson(w http.ResponseWriter, r *http.Request) {
 
 	type responseDataElement struct {
 		CorrelationID string `json:"correlation_id"`
-		ShortURL      string `json:"short_url"`
+		ShortURL      string `json:"short_url,omitempty"`
+		Error         string `json:"error,omitempty"`
+		*urlMetadata
 	}
 
 	var responseData = make([]responseDataElement, len(requestData))
 
 	userID := r.Context().Value(utils.ContextKey("userID")).(string)
 
-	correlationIDs := make([]string, len(requestData))
-	urls := make([]repository.URL, len(requestData))
+	indexes := make([]int, 0, len(requestData))
+	urls := make([]repository.URL, 0, len(requestData))
+	failStatus := http.StatusBadRequest
 
 	for index, element := range requestData {
-		url, err := repository.MakeURL(element.OriginalURL, userID)
+		responseData[index].CorrelationID = element.CorrelationID
+
+		url, invalid, err := makeBatchURL(r, userID, element)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		urls[index] = url
-		correlationIDs[index] = element.CorrelationID
+		if invalid != nil {
+			responseData[index].Error = invalid.Error()
+			if errors.Is(invalid, screening.ErrorBlocked) {
+				failStatus = http.StatusForbidden
+			}
+			continue
+		}
+
+		urls = append(urls, url)
+		indexes = append(indexes, index)
 	}
 
-	urls, err = repository.GlobalRepository.InsertMany(r.Context(), urls)
+	if len(urls) > 0 {
+		urls, err = repository.GlobalRepository.InsertMany(r.Context(), urls, quota.Limits(userID)...)
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fetchPages(urls...)
 	}
 
+	saved := 0
 	for index, url := range urls {
-		responseData[index] = responseDataElement{CorrelationID: correlationIDs[index], ShortURL: url.Short}
+		// Urls which don't fit quota are returned empty.
+		if len(url.ID) == 0 {
+			responseData[indexes[index]].Error = quota.ErrorQuotaExceeded.Error()
+			failStatus = http.StatusForbidden
+			continue
+		}
+
+		saved++
+		metadata := makeURLMetadata(url)
+		responseData[indexes[index]].ShortURL = url.Short
+		responseData[indexes[index]].urlMetadata = &metadata
+	}
+
+	var status = http.StatusCreated
+	if saved == 0 {
+		status = failStatus
 	}
 
 	responseBody, err := json.Marshal(responseData)
@@ -259,7 +767,7 @@ func BatchURLJson(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	w.Write(responseBody)
 }
 
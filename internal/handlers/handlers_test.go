@@ -2,6 +2,8 @@ package handlers_test
 
 import (
 	"context"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
 	"github.com/LorezV/url-shorter.git/internal/handlers"
 	"github.com/LorezV/url-shorter.git/internal/middlewares"
 	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
@@ -9,7 +11,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -117,10 +121,28 @@ func TestCreateURL(t *testing.T) {
 				statusCode: http.StatusBadRequest,
 			},
 		},
+		{
+			name: "Test POST request with javascript url.",
+			path: "/",
+			body: "javascript:alert(1)",
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "Test POST request with relative url.",
+			path: "/",
+			body: "/api/user/urls",
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
 			r := chi.NewRouter()
 			r.Use(middlewares.Authorization)
 			r.Post("/", handlers.CreateURL)
@@ -180,6 +202,8 @@ func TestCreateURLJson(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
 			r := chi.NewRouter()
 			r.Use(middlewares.Authorization)
 			r.Post("/api/shorten", handlers.CreateURLJson)
@@ -215,6 +239,8 @@ func TestGetUserUrls(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
 			r := chi.NewRouter()
 			r.Use(middlewares.Authorization)
 			r.Get("/api/user/urls", handlers.GetUserUrls)
@@ -259,6 +285,8 @@ func TestBatchURLJson(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
 			r := chi.NewRouter()
 			r.Use(middlewares.Authorization)
 			r.Post("/api/shorten/batch", handlers.BatchURLJson)
@@ -301,3 +329,231 @@ func testRequest(t *testing.T, ts *httptest.Server, method, path string, body io
 
 	return *resp, string(respBody)
 }
+
+func TestBatchURLJsonQuota(t *testing.T) {
+	config.AppConfig.QuotaMaxLinks = 1
+	defer func() { config.AppConfig.QuotaMaxLinks = 0 }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Post("/api/shorten/batch", handlers.BatchURLJson)
+	r.Get("/api/user/quota", handlers.GetUserQuota)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch", "owner0000001", `[{"correlation_id":"1", "original_url": "https://practicum.yandex.ru"}, {"correlation_id":"2", "original_url": "https://yandex.ru"}]`, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Contains(t, body, `{"correlation_id":"2","error":"links quota exceeded"}`)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/quota", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"used":1,"limit":1,"remaining":0`)
+
+	// Existing url doesn't take quota, new one is rejected.
+	resp, body = testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch", "owner0000001", `[{"correlation_id":"1", "original_url": "https://practicum.yandex.ru"}, {"correlation_id":"2", "original_url": "https://yandex.ru"}]`, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Contains(t, body, `{"correlation_id":"1","short_url":`)
+	assert.Contains(t, body, `{"correlation_id":"2","error":"links quota exceeded"}`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch", "owner0000001", `[{"correlation_id":"2", "original_url": "https://yandex.ru"}]`, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestCreateURLQuota(t *testing.T) {
+	config.AppConfig.QuotaMaxLinks = 5
+	defer func() { config.AppConfig.QuotaMaxLinks = 0 }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Post("/", handlers.CreateURL)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		statuses = map[int]int{}
+	)
+
+	for index := 0; index < 20; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			resp, _ := testUserRequest(t, ts, http.MethodPost, "/", "owner0000001", fmt.Sprintf("https://example.com/%d", index), nil)
+
+			mutex.Lock()
+			statuses[resp.StatusCode]++
+			mutex.Unlock()
+		}(index)
+	}
+
+	wg.Wait()
+	assert.Equal(t, map[int]int{http.StatusCreated: 5, http.StatusForbidden: 15}, statuses)
+
+	// Duplicate is reported before quota.
+	urls, _, err := repository2.GlobalRepository.GetAllByUser(context.Background(), "owner0000001", repository2.ListOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, urls)
+
+	resp, short := testUserRequest(t, ts, http.MethodPost, "/", "owner0000001", urls[0].Original, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, urls[0].Short, short)
+}
+
+func TestCreateURLNormalizedDuplicate(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Post("/", handlers.CreateURL)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, short := testRequest(t, ts, http.MethodPost, "/", strings.NewReader("https://practicum.yandex.ru"))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, duplicate := testRequest(t, ts, http.MethodPost, "/", strings.NewReader(" HTTPS://Practicum.Yandex.ru/ "))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, short, duplicate)
+}
+
+func TestGetURLRedirectType(t *testing.T) {
+	config.AppConfig.RedirectCacheMaxAge = time.Hour
+	defer func() { config.AppConfig.RedirectCacheMaxAge = 0 }()
+
+	soon := time.Now().Add(10 * time.Minute)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		url          repository2.URL
+		statusCode   int
+		cacheControl string
+	}{
+		{
+			name:         "Default temporary redirect is not cached.",
+			url:          repository2.URL{ID: "tmp001", Original: "https://practicum.yandex.ru"},
+			statusCode:   http.StatusTemporaryRedirect,
+			cacheControl: "^private, no-cache$",
+		},
+		{
+			name:         "Permanent redirect is cached publicly.",
+			url:          repository2.URL{ID: "perm01", Original: "https://practicum.yandex.ru", RedirectType: http.StatusMovedPermanently},
+			statusCode:   http.StatusMovedPermanently,
+			cacheControl: "^public, max-age=3600$",
+		},
+		{
+			name:         "Permanent redirect is cached until expiration.",
+			url:          repository2.URL{ID: "perm02", Original: "https://practicum.yandex.ru", RedirectType: http.StatusPermanentRedirect, ExpiresAt: &soon},
+			statusCode:   http.StatusPermanentRedirect,
+			cacheControl: "^public, max-age=59[0-9]$",
+		},
+		{
+			name:         "Permanent redirect limited by clicks is not cached.",
+			url:          repository2.URL{ID: "perm03", Original: "https://practicum.yandex.ru", RedirectType: http.StatusMovedPermanently, MaxClicks: 5},
+			statusCode:   http.StatusMovedPermanently,
+			cacheControl: "^private, no-cache$",
+		},
+		{
+			name:       "Exhausted link is gone.",
+			url:        repository2.URL{ID: "used01", Original: "https://practicum.yandex.ru", Clicks: 1, MaxClicks: 1},
+			statusCode: http.StatusGone,
+		},
+		{
+			name:       "Expired link is gone.",
+			url:        repository2.URL{ID: "old001", Original: "https://practicum.yandex.ru", ExpiresAt: &past},
+			statusCode: http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+			repository2.GlobalRepository.Insert(context.Background(), tt.url)
+
+			r := chi.NewRouter()
+			r.Get("/{id}", handlers.GetURL)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			resp, _ := testRequest(t, ts, http.MethodGet, "/"+tt.url.ID, nil)
+
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+			if len(tt.cacheControl) > 0 {
+				assert.Regexp(t, tt.cacheControl, resp.Header.Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestCreateURLJsonRedirectType(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Post("/api/shorten", handlers.CreateURLJson)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, _ := testRequest(t, ts, http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://practicum.yandex.ru","redirect_type":303}`))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://practicum.yandex.ru","redirect_type":308}`))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestMaxClicksURL(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Get("/{id}", handlers.GetURL)
+	r.Post("/api/shorten", handlers.CreateURLJson)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, _ := testRequest(t, ts, http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://practicum.yandex.ru","max_clicks":-1}`))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://practicum.yandex.ru/once","max_clicks":3}`))
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://practicum.yandex.ru/once")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), url.MaxClicks)
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		statuses = map[int]int{}
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := makeClient().Get(ts.URL + "/" + url.ID)
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+
+			mutex.Lock()
+			statuses[resp.StatusCode]++
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{http.StatusTemporaryRedirect: 3, http.StatusGone: 17}, statuses)
+
+	url, _ = repository2.GlobalRepository.Get(context.Background(), url.ID)
+	assert.Equal(t, int64(3), url.Clicks)
+}
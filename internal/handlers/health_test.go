@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/healthcheck"
+	"github.com/LorezV/url-shorter.git/internal/jobs"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserBrokenUrls(t *testing.T) {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer destination.Close()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	for _, path := range []string{"/old", "/gone"} {
+		resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"`+destination.URL+path+`"}`, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	resp, _ := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/broken", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	jobs.CheckHealth(context.Background(), time.Now(), 100, healthcheck.MakeChecker(time.Second, 2, 0, true))
+
+	type element struct {
+		OriginalURL string              `json:"original_url"`
+		Health      *repository2.Health `json:"health"`
+	}
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls?order=asc", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var all []element
+	require.NoError(t, json.Unmarshal([]byte(body), &all))
+	require.Len(t, all, 2)
+	require.NotNil(t, all[0].Health)
+	assert.Equal(t, http.StatusOK, all[0].Health.Status)
+	assert.Equal(t, []string{destination.URL + "/new"}, all[0].Health.Redirects)
+	assert.False(t, all[0].Health.Broken)
+
+	for _, path := range []string{"/api/user/urls/broken", "/api/user/urls?broken=true"} {
+		resp, body = testUserRequest(t, ts, http.MethodGet, path, "owner0000001", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+
+		var broken []element
+		require.NoError(t, json.Unmarshal([]byte(body), &broken))
+		require.Len(t, broken, 1)
+		assert.Equal(t, destination.URL+"/gone", broken[0].OriginalURL)
+		assert.Equal(t, http.StatusNotFound, broken[0].Health.Status)
+		assert.True(t, broken[0].Health.Broken)
+	}
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/broken", "stranger0001", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls?broken=maybe", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits of url title and description length in characters.
+const (
+	maxTitleLength       = 256
+	maxDescriptionLength = 2048
+)
+
+// urlMetadata contains timestamps, creator, description, tags, folder, options, destination health and page of url shown in user responses.
+type urlMetadata struct {
+	CreatedAt        *time.Time               `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time               `json:"updated_at,omitempty"`
+	CreatorIP        string                   `json:"creator_ip,omitempty"`
+	CreatorUserAgent string                   `json:"creator_user_agent,omitempty"`
+	Title            string                   `json:"title,omitempty"`
+	Description      string                   `json:"description,omitempty"`
+	Tags             []string                 `json:"tags,omitempty"`
+	FolderID         int64                    `json:"folder_id,omitempty"`
+	PasswordRequired bool                     `json:"password_required,omitempty"`
+	MaxClicks        int64                    `json:"max_clicks,omitempty"`
+	NotBefore        *time.Time               `json:"not_before,omitempty"`
+	NotAfter         *time.Time               `json:"not_after,omitempty"`
+	FallbackURL      string                   `json:"fallback_url,omitempty"`
+	Rules            []repository.RoutingRule `json:"rules,omitempty"`
+	Variants         []repository.Variant     `json:"variants,omitempty"`
+	GeoRules         []repository.GeoRule     `json:"geo_rules,omitempty"`
+	GeoDefault       string                   `json:"geo_default,omitempty"`
+	QueryPassthrough *bool                    `json:"query_passthrough,omitempty"`
+	QueryConflict    string                   `json:"query_conflict,omitempty"`
+	UTM              map[string]string        `json:"utm,omitempty"`
+	Health           *repository.Health       `json:"health,omitempty"`
+	Page             *repository.Page         `json:"page,omitempty"`
+}
+
+func makeURLMetadata(url repository.URL) urlMetadata {
+	metadata := urlMetadata{
+		CreatorIP:        url.CreatorIP,
+		CreatorUserAgent: url.CreatorUserAgent,
+		Title:            url.Title,
+		Description:      url.Description,
+		Tags:             url.Tags,
+		FolderID:         url.FolderID,
+		PasswordRequired: len(url.PasswordHash) > 0,
+		MaxClicks:        url.MaxClicks,
+		NotBefore:        url.NotBefore,
+		NotAfter:         url.NotAfter,
+		FallbackURL:      url.FallbackURL,
+		Rules:            url.Rules,
+		Variants:         url.Variants,
+		GeoRules:         url.GeoRules,
+		GeoDefault:       url.GeoDefault,
+		QueryPassthrough: url.QueryPassthrough,
+		QueryConflict:    url.QueryConflict,
+		UTM:              url.UTM,
+		Health:           url.Health,
+		Page:             url.Page,
+	}
+
+	if !url.CreatedAt.IsZero() {
+		metadata.CreatedAt = &url.CreatedAt
+	}
+
+	if !url.UpdatedAt.IsZero() {
+		metadata.UpdatedAt = &url.UpdatedAt
+	}
+
+	return metadata
+}
+
+// validateMetadata checks length of url title and description.
+func validateMetadata(title, description string) error {
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return fmt.Errorf("title must be at most %d characters", maxTitleLength)
+	}
+
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", maxDescriptionLength)
+	}
+
+	return nil
+}
+
+// makeURL creates url entity remembering ip and user agent of client which created it.
+func makeURL(r *http.Request, original, userID string) (repository.URL, error) {
+	url, err := repository.MakeURL(original, userID)
+	if err != nil {
+		return url, err
+	}
+
+	url.CreatorIP = clientip.FromRequest(r)
+	url.CreatorUserAgent = r.UserAgent()
+
+	return url, nil
+}
+
+type userURLResponse struct {
+	ID           string     `json:"id"`
+	ShortURL     string     `json:"short_url"`
+	OriginalURL  string     `json:"original_url"`
+	RedirectType int        `json:"redirect_type,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Version      int        `json:"version"`
+	urlMetadata
+}
+
+func makeUserURLResponse(url repository.URL) userURLResponse {
+	return userURLResponse{
+		ID:           url.ID,
+		ShortURL:     url.Short,
+		OriginalURL:  url.Original,
+		RedirectType: url.RedirectType,
+		ExpiresAt:    url.ExpiresAt,
+		Version:      url.Version,
+		urlMetadata:  makeURLMetadata(url),
+	}
+}
+
+// etag returns entity tag of url version.
+func etag(url repository.URL) string {
+	return fmt.Sprintf(`"%s-v%d"`, url.ID, url.Version)
+}
+
+// matchesETag reports whether If-Match or If-None-Match header value lists entity tag, empty header matches any.
+// Weak comparison treats W/ tags as equal to strong ones, it's used for If-None-Match, If-Match requires strong
+// comparison by RFC 9110, so weak tags never match it.
+func matchesETag(header, tag string, weak bool) bool {
+	if len(header) == 0 {
+		return true
+	}
+
+	for {
+		header = strings.TrimLeft(header, " \t,")
+		if len(header) == 0 {
+			return false
+		}
+		if header[0] == '*' {
+			return true
+		}
+
+		isWeak := strings.HasPrefix(header, "W/")
+		header = strings.TrimPrefix(header, "W/")
+
+		// Entity tag is quoted and may contain commas, so list is scanned tag by tag.
+		if header[0] != '"' {
+			return false
+		}
+		end := strings.IndexByte(header[1:], '"')
+		if end < 0 {
+			return false
+		}
+
+		value := header[:end+2]
+		header = header[end+2:]
+
+		if value == tag && (weak || !isWeak) {
+			return true
+		}
+	}
+}
+
+// decodeNullableTime decodes time from PATCH request field, missing field keeps current value and null clears it.
+func decodeNullableTime(raw json.RawMessage, current *time.Time) (*time.Time, error) {
+	if len(raw) == 0 {
+		return current, nil
+	}
+
+	var value *time.Time
+	err := json.Unmarshal(raw, &value)
+
+	return value, err
+}
+
+// getOwnURL returns user's not deleted url by id from path or writes 404 to response.
+func getOwnURL(w http.ResponseWriter, r *http.Request) (repository.URL, bool) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	url, ok := repository.GlobalRepository.Get(r.Context(), chi.URLParam(r, "id"))
+	if !ok || url.IsDeleted || url.UserID != userID {
+		http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		return url, false
+	}
+
+	return url, true
+}
+
+// GetUserURL handler returns user's url by id with ETag header for following PATCH request.
+func GetUserURL(w http.ResponseWriter, r *http.Request) {
+	url, ok := getOwnURL(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", etag(url))
+	writeJSON(w, http.StatusOK, makeUserURLResponse(url))
+}
+
+// PatchUserURL handler changes destination, redirect type, expiration, title, description, password and activation
+// window, routing rules, variants, geo rules and query options of user's url. Empty password removes protection, null window bounds remove them,
+// rules, variants and geo rules are replaced as a whole, changed variants reset their stats.
+// If-Match header with url ETag protects from overwriting concurrent changes.
+func PatchUserURL(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		OriginalURL  *string                   `json:"original_url"`
+		RedirectType *int                      `json:"redirect_type"`
+		ExpiresAt    json.RawMessage           `json:"expires_at"`
+		Title        *string                   `json:"title"`
+		Description  *string                   `json:"description"`
+		Password     *string                   `json:"password"`
+		NotBefore    json.RawMessage           `json:"not_before"`
+		NotAfter     json.RawMessage           `json:"not_after"`
+		FallbackURL  *string                   `json:"fallback_url"`
+		Rules        *[]repository.RoutingRule `json:"rules"`
+		Variants     *[]repository.Variant     `json:"variants"`
+		GeoRules     *[]repository.GeoRule     `json:"geo_rules"`
+		GeoDefault   *string                   `json:"geo_default"`
+		// QueryPassthrough is raw to tell null, which restores server default, from missing field.
+		QueryPassthrough json.RawMessage    `json:"query_passthrough"`
+		QueryConflict    *string            `json:"query_conflict"`
+		UTM              *map[string]string `json:"utm"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url, ok := getOwnURL(w, r)
+	if !ok {
+		return
+	}
+
+	if !matchesETag(r.Header.Get("If-Match"), etag(url), false) {
+		http.Error(w, "URL was changed by another request.", http.StatusPreconditionFailed)
+		return
+	}
+
+	version, previous := url.Version, url.Original
+
+	if data.OriginalURL != nil {
+		original, err := urlnorm.Normalize(*data.OriginalURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, blocked := screening.GlobalBlocklist.Check(original); blocked {
+			http.Error(w, screening.ErrorBlocked.Error(), http.StatusForbidden)
+			return
+		}
+
+		url.Original = original
+	}
+
+	if data.RedirectType != nil {
+		url.RedirectType = *data.RedirectType
+	}
+
+	var expiresAt *time.Time
+
+	if len(data.ExpiresAt) > 0 {
+		if err := json.Unmarshal(data.ExpiresAt, &expiresAt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		url.ExpiresAt = expiresAt
+	}
+
+	if data.Title != nil {
+		url.Title = *data.Title
+	}
+
+	if data.Description != nil {
+		url.Description = *data.Description
+	}
+
+	if data.Password != nil {
+		hash, err := hashPassword(*data.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		url.PasswordHash = hash
+	}
+
+	if len(data.NotBefore) > 0 || len(data.NotAfter) > 0 {
+		var err error
+
+		if url.NotBefore, err = decodeNullableTime(data.NotBefore, url.NotBefore); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if url.NotAfter, err = decodeNullableTime(data.NotAfter, url.NotAfter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateSchedule(url.NotBefore, url.NotAfter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if data.FallbackURL != nil {
+		fallbackURL, err := normalizeFallbackURL(*data.FallbackURL)
+		if err != nil {
+			http.Error(w, err.Error(), destinationErrorStatus(err))
+			return
+		}
+
+		url.FallbackURL = fallbackURL
+	}
+
+	if data.Rules != nil {
+		rules, err := normalizeRules(*data.Rules)
+		if err != nil {
+			http.Error(w, err.Error(), destinationErrorStatus(err))
+			return
+		}
+
+		url.Rules = rules
+	}
+
+	if data.Variants != nil {
+		variants, err := normalizeVariants(*data.Variants)
+		if err != nil {
+			http.Error(w, err.Error(), destinationErrorStatus(err))
+			return
+		}
+
+		url.Variants = variants
+	}
+
+	if data.GeoRules != nil {
+		geoRules, err := normalizeGeoRules(*data.GeoRules)
+		if err != nil {
+			http.Error(w, err.Error(), destinationErrorStatus(err))
+			return
+		}
+
+		url.GeoRules = geoRules
+	}
+
+	if data.GeoDefault != nil {
+		geoDefault, err := normalizeGeoDefault(*data.GeoDefault)
+		if err != nil {
+			http.Error(w, err.Error(), destinationErrorStatus(err))
+			return
+		}
+
+		url.GeoDefault = geoDefault
+	}
+
+	if len(data.QueryPassthrough) > 0 {
+		url.QueryPassthrough = nil
+		if err := json.Unmarshal(data.QueryPassthrough, &url.QueryPassthrough); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if data.QueryConflict != nil {
+		if err := validateQueryConflict(*data.QueryConflict); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		url.QueryConflict = *data.QueryConflict
+	}
+
+	if data.UTM != nil {
+		utm, err := normalizeUTM(*data.UTM)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		url.UTM = utm
+	}
+
+	if err := validateLinkOptions(url.RedirectType, expiresAt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateMetadata(url.Title, url.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url, err := repository.GlobalRepository.Update(r.Context(), url, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrorURLNotFound), errors.Is(err, repository.ErrorURLForbidden):
+			http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		case errors.Is(err, repository.ErrorURLVersionMismatch):
+			http.Error(w, "URL was changed by another request.", http.StatusPreconditionFailed)
+		case errors.Is(err, repository.ErrorURLDuplicate):
+			http.Error(w, "URL with this original already exists.", http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Metadata is fetched only for new destination, so edits don't fill unfurl queue.
+	if url.Original != previous {
+		fetchPages(url)
+	}
+
+	w.Header().Set("ETag", etag(url))
+	writeJSON(w, http.StatusOK, makeUserURLResponse(url))
+}
+
+// GetUserURLHistory handler returns all versions of user's url destination from oldest to current.
+func GetUserURLHistory(w http.ResponseWriter, r *http.Request) {
+	url, ok := getOwnURL(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := repository.GlobalRepository.GetHistory(r.Context(), url.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
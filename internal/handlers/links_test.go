@@ -0,0 +1,272 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/handlers"
+	"github.com/LorezV/url-shorter.git/internal/middlewares"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userCookie returns valid auth cookie of user.
+func userCookie(userID string) *http.Cookie {
+	return &http.Cookie{Name: "userID", Value: userID + hex.EncodeToString(utils.EncodeUserID(userID))}
+}
+
+func testUserRequest(t *testing.T, ts *httptest.Server, method, path, userID string, body string, headers map[string]string) (http.Response, string) {
+	req, err := makeRequest(ts, method, path, strings.NewReader(body))
+	require.NoError(t, err)
+
+	req.AddCookie(userCookie(userID))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := makeClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return *resp, string(respBody)
+}
+
+func makeLinksServer() *httptest.Server {
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Get("/{id}", handlers.GetURL)
+	r.Post("/{id}/conversion", handlers.RecordConversion)
+	r.Route("/api/user/urls", func(r chi.Router) {
+		r.Get("/", handlers.GetUserUrls)
+		r.Delete("/", handlers.DeleteUserUrls)
+		r.Get("/trash", handlers.GetUserTrash)
+		r.Get("/search", handlers.SearchUserUrls)
+		r.Get("/broken", handlers.GetUserBrokenUrls)
+		r.Post("/import", handlers.ImportUserUrls)
+		r.Get("/export", handlers.ExportUserUrls)
+		r.Post("/restore", handlers.RestoreUserUrls)
+		r.Get("/{id}", handlers.GetUserURL)
+		r.Patch("/{id}", handlers.PatchUserURL)
+		r.Get("/{id}/history", handlers.GetUserURLHistory)
+		r.Get("/{id}/stats", handlers.GetUserURLStats)
+		r.Get("/{id}/qr", handlers.GetUserURLQR)
+		r.Put("/{id}/tags", handlers.SetUserURLTags)
+		r.Put("/{id}/folder", handlers.SetUserURLFolder)
+	})
+	r.Post("/api/shorten", handlers.CreateURLJson)
+	r.Post("/api/shorten/batch", handlers.BatchURLJson)
+	r.Post("/api/shorten/batch/stream", handlers.BatchURLStream)
+	r.Get("/api/user/tags", handlers.GetUserTags)
+	r.Patch("/api/user/tags/{tag}", handlers.RenameUserTag)
+	r.Delete("/api/user/tags/{tag}", handlers.DeleteUserTag)
+	r.Get("/api/user/folders", handlers.GetUserFolders)
+	r.Post("/api/user/folders", handlers.CreateUserFolder)
+	r.Patch("/api/user/folders/{folderID}", handlers.UpdateUserFolder)
+	r.Delete("/api/user/folders/{folderID}", handlers.DeleteUserFolder)
+
+	return httptest.NewServer(r)
+}
+
+func TestPatchUserURL(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "promo1", Original: "https://example.com/tpyo", Short: "http://127.0.0.1:8080/promo1", UserID: "owner0000001"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "other1", Original: "https://example.com/other", Short: "http://127.0.0.1:8080/other1", UserID: "owner0000001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "stranger0001", `{"original_url":"https://evil.example"}`, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/promo1", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	tag := resp.Header.Get("ETag")
+	require.NotEmpty(t, tag)
+
+	resp, body := testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "owner0000001", `{"original_url":"https://example.com/typo","redirect_type":301}`, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, tag, resp.Header.Get("ETag"))
+	assert.Contains(t, body, `"original_url":"https://example.com/typo"`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "owner0000001", `{"redirect_type":302}`, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	// If-Match uses strong comparison, tag list may contain quoted commas.
+	current := `"promo1-v2"`
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "owner0000001", `{"redirect_type":302}`, map[string]string{"If-Match": "W/" + current})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "owner0000001", `{"redirect_type":301}`, map[string]string{"If-Match": `"a,b", W/"x", ` + current})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/promo1", "owner0000001", `{"original_url":"https://example.com/other"}`, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/promo1", nil)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://example.com/typo", resp.Header.Get("Location"))
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/promo1/history", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var revisions []repository2.URLRevision
+	require.NoError(t, json.Unmarshal([]byte(body), &revisions))
+	require.Len(t, revisions, 3)
+	assert.Equal(t, "https://example.com/tpyo", revisions[0].Original)
+	assert.NotNil(t, revisions[0].ReplacedAt)
+	assert.Equal(t, "https://example.com/typo", revisions[1].Original)
+	assert.Equal(t, 2, revisions[1].Version)
+}
+
+func TestUserTrash(t *testing.T) {
+	config.AppConfig.TrashGracePeriod = time.Hour
+	defer func() { config.AppConfig.TrashGracePeriod = 0 }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "trash1", Original: "https://example.com/1", Short: "http://127.0.0.1:8080/trash1", UserID: "owner0000001"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "trash2", Original: "https://example.com/2", Short: "http://127.0.0.1:8080/trash2", UserID: "owner0000001"})
+	repository2.GlobalRepository.DeleteManyByUser(context.Background(), []string{"trash1", "trash2"}, "owner0000001")
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/trash", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"id":"trash1"`)
+	assert.Contains(t, body, `"purge_at"`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/trash", "stranger0001", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodPost, "/api/user/urls/restore", "owner0000001", `["trash1", "unknown"]`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"restored":["trash1"]}`, body)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/trash1", nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/trash2", nil)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	count, err := repository2.GlobalRepository.PurgeDeleted(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/trash2", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGetUserUrlsPagination(t *testing.T) {
+	config.AppConfig.BaseURL = "http://127.0.0.1:8080"
+	defer func() { config.AppConfig.BaseURL = "" }()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for index, id := range []string{"page01", "page02", "page03"} {
+		repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: id, Original: "https://example.com/" + id, Short: "http://127.0.0.1:8080/" + id, UserID: "owner0000001", CreatedAt: created.Add(time.Duration(index) * time.Hour)})
+	}
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "page04", Original: "https://docs.example.org/page04", Short: "http://127.0.0.1:8080/page04", UserID: "owner0000001", CreatedAt: created.Add(-time.Hour)})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	var urls []struct {
+		ShortURL string `json:"short_url"`
+	}
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls?limit=2", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 2)
+	assert.Equal(t, "http://127.0.0.1:8080/page03", urls[0].ShortURL)
+	assert.Equal(t, "http://127.0.0.1:8080/page02", urls[1].ShortURL)
+
+	link := resp.Header.Get("Link")
+	require.Regexp(t, `^<http://127.0.0.1:8080/api/user/urls\?.*cursor=.+>; rel="next"$`, link)
+	next := strings.TrimPrefix(link[:strings.Index(link, ">")], "<http://127.0.0.1:8080")
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, next, "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Link"))
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 2)
+	assert.Equal(t, "http://127.0.0.1:8080/page01", urls[0].ShortURL)
+	assert.Equal(t, "http://127.0.0.1:8080/page04", urls[1].ShortURL)
+
+	testRequest(t, ts, http.MethodGet, "/page02", nil)
+	testRequest(t, ts, http.MethodGet, "/page02", nil)
+	testRequest(t, ts, http.MethodGet, "/page01", nil)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls?sort=clicks&limit=2", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 2)
+	assert.Equal(t, "http://127.0.0.1:8080/page02", urls[0].ShortURL)
+	assert.Equal(t, "http://127.0.0.1:8080/page01", urls[1].ShortURL)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls?domain=example.org&q=PAGE", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "http://127.0.0.1:8080/page04", urls[0].ShortURL)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls?order=asc&created_after=2023-01-01T00:30:00Z&created_before=2023-01-01T01:30:00Z", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "http://127.0.0.1:8080/page02", urls[0].ShortURL)
+
+	for _, path := range []string{"/api/user/urls?limit=0", "/api/user/urls?sort=title", "/api/user/urls?cursor=broken", "/api/user/urls?created_after=yesterday"} {
+		resp, _ = testUserRequest(t, ts, http.MethodGet, path, "owner0000001", "", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+}
+
+func TestURLMetadata(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Post("/api/shorten/batch", handlers.BatchURLJson)
+	r.Get("/api/user/urls", handlers.GetUserUrls)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	headers := map[string]string{"User-Agent": "metadata-test/1.0"}
+	body := `[{"correlation_id":"1","original_url":"https://example.com/sale","title":"Sale","description":"Autumn sale landing"},{"correlation_id":"2","original_url":"https://example.com/long","title":"` + strings.Repeat("x", 257) + `"}]`
+
+	resp, respBody := testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch", "owner0000001", body, headers)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var batch []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(respBody), &batch))
+	require.Len(t, batch, 2)
+	assert.Equal(t, "Sale", batch[0]["title"])
+	assert.Equal(t, "metadata-test/1.0", batch[0]["creator_user_agent"])
+	assert.Equal(t, "127.0.0.1", batch[0]["creator_ip"])
+	assert.NotEmpty(t, batch[0]["created_at"])
+	assert.NotEmpty(t, batch[1]["error"])
+	assert.NotContains(t, batch[1], "created_at")
+
+	resp, respBody = testUserRequest(t, ts, http.MethodGet, "/api/user/urls", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var urls []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(respBody), &urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "Autumn sale landing", urls[0]["description"])
+	assert.Equal(t, urls[0]["created_at"], urls[0]["updated_at"])
+}
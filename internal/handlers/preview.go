@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// previewSuffix turns short link into its preview page, e.g. /abc+.
+const previewSuffix = "+"
+
+// previewData is data of preview page template.
+type previewData struct {
+	Short       string
+	Destination string
+	Host        string
+	Title       string
+	Description string
+	CreatedAt   time.Time
+	Clicks      int64
+	Protected   bool
+	Blocked     bool
+	Inactive    bool
+}
+
+// previewID returns link id from path parameter and reports whether preview page is requested
+// by suffix or preview query parameter instead of redirect.
+func previewID(r *http.Request, id string) (string, bool) {
+	if strings.HasSuffix(id, previewSuffix) {
+		return strings.TrimSuffix(id, previewSuffix), true
+	}
+
+	preview, _ := strconv.ParseBool(r.URL.Query().Get("preview"))
+
+	return id, preview
+}
+
+// renderPreview writes preview page of link with OpenGraph and Twitter card tags. Title and description of link
+// fall back to fetched ones of destination page. Destination of password protected link isn't shown,
+// links routed by client show their default destination.
+func renderPreview(w http.ResponseWriter, url repository.URL, now time.Time) {
+	data := previewData{
+		Short:       url.Short,
+		Title:       url.Title,
+		Description: url.Description,
+		CreatedAt:   url.CreatedAt,
+		Clicks:      url.Clicks,
+		Protected:   len(url.PasswordHash) > 0,
+		Inactive:    scheduleState(url, now) != scheduleActive,
+	}
+
+	if !data.Protected {
+		data.Destination = url.Original
+		if parsed, err := neturl.Parse(url.Original); err == nil {
+			data.Host = parsed.Hostname()
+		}
+		_, data.Blocked = screening.GlobalBlocklist.Check(url.Original)
+
+		if url.Page != nil && len(data.Title) == 0 {
+			data.Title = url.Page.Title
+		}
+		if url.Page != nil && len(data.Description) == 0 {
+			data.Description = url.Page.Description
+		}
+	}
+
+	if len(data.Title) == 0 {
+		data.Title = data.Host
+	}
+	if len(data.Title) == 0 {
+		data.Title = "Short link"
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	renderPage(w, http.StatusOK, previewTemplate, data)
+}
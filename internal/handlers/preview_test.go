@@ -0,0 +1,58 @@
+package handlers_test
+
+import (
+	"context"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewURL(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/launch","title":"Launch <party>","description":"See you there"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/private","password":"s3cret!"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/launch")
+	require.True(t, ok)
+	protected, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/private")
+	require.True(t, ok)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/"+url.ID, nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	for _, path := range []string{"/" + url.ID + "+", "/" + url.ID + "?preview=1"} {
+		resp, body := testRequest(t, ts, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Contains(t, body, `<meta property="og:title" content="Launch &lt;party&gt;">`)
+		assert.Contains(t, body, `<meta property="og:description" content="See you there">`)
+		assert.Contains(t, body, `<meta property="og:url" content="`+url.Short+`">`)
+		assert.Contains(t, body, `<meta name="twitter:card" content="summary">`)
+		assert.Contains(t, body, "<code>https://example.com/launch</code>")
+		assert.Contains(t, body, "Created "+url.CreatedAt.UTC().Format("2006-01-02")+", followed 1 times.")
+	}
+
+	url, ok = repository2.GlobalRepository.Get(context.Background(), url.ID)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), url.Clicks)
+
+	resp, body := testRequest(t, ts, http.MethodGet, "/"+protected.ID+"+", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body, "https://example.com/private")
+	assert.Contains(t, body, "protected by password")
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/missing+", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/ratelimit"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Limits of link password length, bcrypt ignores bytes after 72.
+const (
+	minPasswordLength = 4
+	maxPasswordLength = 72
+)
+
+// unlockCookiePrefix is prefix of cookie name which holds signed proof of entered link password.
+const unlockCookiePrefix = "unlock_"
+
+type unlockPage struct {
+	ID    string
+	Error string
+}
+
+// hashPassword validates link password and returns its bcrypt hash, empty password means no protection.
+func hashPassword(password string) (string, error) {
+	if len(password) == 0 {
+		return "", nil
+	}
+
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("password must be from %d to %d bytes", minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// unlockSignature signs link id and cookie expiration time, password hash is signed too,
+// so changing password invalidates issued cookies.
+func unlockSignature(url repository.URL, expires int64) string {
+	return hex.EncodeToString(utils.Sign(fmt.Sprintf("%s|%d|%s", url.ID, expires, url.PasswordHash)))
+}
+
+// isUnlocked reports whether request has valid unlock cookie of password protected link.
+func isUnlocked(r *http.Request, url repository.URL) bool {
+	cookie, err := r.Cookie(unlockCookiePrefix + url.ID)
+	if err != nil {
+		return false
+	}
+
+	value, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(unlockSignature(url, expires)))
+}
+
+// setUnlockCookie issues unlock cookie of link valid for config.AppConfig.UnlockCookieTTL.
+func setUnlockCookie(w http.ResponseWriter, url repository.URL) {
+	expires := time.Now().Add(config.AppConfig.UnlockCookieTTL)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookiePrefix + url.ID,
+		Value:    fmt.Sprintf("%d.%s", expires.Unix(), unlockSignature(url, expires.Unix())),
+		Path:     "/" + url.ID,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   config.AppConfig.EnableHTTPS,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// renderUnlockPage writes password form of link, it must never be cached.
+func renderUnlockPage(w http.ResponseWriter, status int, url repository.URL, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, status, unlockTemplate, unlockPage{ID: url.ID, Error: message})
+}
+
+// takeUnlockAttempt takes token from link unlock attempts bucket, it reports false and writes 429 to response when attempts are exhausted.
+func takeUnlockAttempt(w http.ResponseWriter, r *http.Request, url repository.URL) bool {
+	if ratelimit.GlobalStore == nil || config.AppConfig.UnlockAttempts <= 0 {
+		return true
+	}
+
+	result, err := ratelimit.GlobalStore.Take(r.Context(), "unlock:"+url.ID, config.AppConfig.UnlockAttempts, config.AppConfig.UnlockPeriod)
+	if err != nil {
+		log.Printf("Can't take unlock attempt for %s: %v", url.ID, err)
+		return true
+	}
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		renderUnlockPage(w, http.StatusTooManyRequests, url, "Too many attempts, try again later.")
+		return false
+	}
+
+	return true
+}
+
+// UnlockURL handler checks password of protected link from form and sets unlock cookie,
+// then redirects client back to short link.
+func UnlockURL(w http.ResponseWriter, r *http.Request) {
+	url, ok := repository.GlobalRepository.Get(r.Context(), chi.URLParam(r, "id"))
+	if !ok {
+		http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		return
+	}
+
+	if url.IsDeleted || isExpired(url, time.Now()) {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	if len(url.PasswordHash) > 0 {
+		if !takeUnlockAttempt(w, r, url) {
+			return
+		}
+
+		err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(r.PostFormValue("password")))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			renderUnlockPage(w, http.StatusForbidden, url, "Wrong password.")
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setUnlockCookie(w, url)
+	}
+
+	http.Redirect(w, r, "/"+url.ID, http.StatusSeeOther)
+}
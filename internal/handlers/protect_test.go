@@ -0,0 +1,173 @@
+package handlers_test
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/handlers"
+	"github.com/LorezV/url-shorter.git/internal/middlewares"
+	"github.com/LorezV/url-shorter.git/internal/ratelimit"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordProtectedURL(t *testing.T) {
+	config.AppConfig.UnlockCookieTTL = time.Hour
+	config.AppConfig.UnlockAttempts = 3
+	config.AppConfig.UnlockPeriod = time.Hour
+	ratelimit.GlobalStore = ratelimit.MakeMemoryStore()
+	defer func() {
+		config.AppConfig.UnlockCookieTTL = 0
+		config.AppConfig.UnlockAttempts = 0
+		config.AppConfig.UnlockPeriod = 0
+		ratelimit.GlobalStore.Close()
+		ratelimit.GlobalStore = nil
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	r := chi.NewRouter()
+	r.Use(middlewares.Authorization)
+	r.Get("/{id}", handlers.GetURL)
+	r.Post("/{id}", handlers.UnlockURL)
+	r.Post("/api/shorten", handlers.CreateURLJson)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://intranet.example.com/salaries","password":"123"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://intranet.example.com/salaries","password":"s3cret!"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	link, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://intranet.example.com/salaries")
+	require.True(t, ok)
+	assert.NotContains(t, link.PasswordHash, "s3cret!")
+
+	unlock := func(password string, cookies ...*http.Cookie) *http.Response {
+		method, body := http.MethodGet, ""
+		if len(password) > 0 {
+			method, body = http.MethodPost, url.Values{"password": {password}}.Encode()
+		}
+
+		req, err := makeRequest(ts, method, "/"+link.ID, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		resp, err := makeClient().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	res := unlock("")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	assert.Empty(t, res.Header.Get("Location"))
+
+	res = unlock("wrong")
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = unlock("s3cret!")
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "/"+link.ID, res.Header.Get("Location"))
+
+	var cookie *http.Cookie
+	for _, value := range res.Cookies() {
+		if value.Name == "unlock_"+link.ID {
+			cookie = value
+		}
+	}
+	require.NotNil(t, cookie)
+
+	res = unlock("", cookie)
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	assert.Equal(t, "https://intranet.example.com/salaries", res.Header.Get("Location"))
+
+	forged := *cookie
+	forged.Value = "9999999999." + strings.Repeat("0", 64)
+	res = unlock("", &forged)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = unlock("wrong")
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = unlock("s3cret!")
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+}
+
+func TestPasswordProtectedBlockedURL(t *testing.T) {
+	config.AppConfig.UnlockCookieTTL = time.Hour
+	config.AppConfig.UnlockAttempts = 3
+	config.AppConfig.UnlockPeriod = time.Hour
+	ratelimit.GlobalStore = ratelimit.MakeMemoryStore()
+	screening.GlobalBlocklist = screening.MakeBlocklist("")
+	defer func() {
+		config.AppConfig.UnlockCookieTTL = 0
+		config.AppConfig.UnlockAttempts = 0
+		config.AppConfig.UnlockPeriod = 0
+		ratelimit.GlobalStore.Close()
+		ratelimit.GlobalStore = nil
+		screening.GlobalBlocklist = screening.MakeBlocklist("")
+	}()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret!"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "locked1", Original: "https://login.evil.example/secret-path", Short: "http://127.0.0.1:8080/locked1", PasswordHash: string(hash)})
+
+	_, err = screening.GlobalBlocklist.Add(screening.Entry{Type: "domain", Value: "evil.example"})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/{id}", handlers.GetURL)
+	r.Post("/{id}", handlers.UnlockURL)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	// Blocked destination isn't shown before link is unlocked.
+	locked, body := testRequest(t, ts, http.MethodGet, "/locked1", nil)
+	assert.Equal(t, http.StatusOK, locked.StatusCode)
+	assert.NotContains(t, body, "evil.example")
+	assert.NotContains(t, body, "may be harmful")
+
+	req, err := makeRequest(ts, http.MethodPost, "/locked1", strings.NewReader(url.Values{"password": {"s3cret!"}}.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := makeClient().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	req, err = makeRequest(ts, http.MethodGet, "/locked1", nil)
+	require.NoError(t, err)
+	for _, cookie := range resp.Cookies() {
+		req.AddCookie(cookie)
+	}
+	resp, err = makeClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+	assert.Contains(t, string(page), "may be harmful")
+}
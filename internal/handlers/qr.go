@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/qr"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// qrContentTypes maps supported QR code formats to their content types.
+var qrContentTypes = map[string]string{
+	"png": "image/png",
+	"svg": "image/svg+xml",
+}
+
+// parseQROptions reads format, size, level, margin, fg and bg query parameters of QR code request,
+// missing parameters are defaults.
+func parseQROptions(r *http.Request) (string, qr.Options, error) {
+	query := r.URL.Query()
+	options := qr.DefaultOptions()
+
+	format := strings.ToLower(query.Get("format"))
+	if len(format) == 0 {
+		format = "png"
+	}
+	if _, ok := qrContentTypes[format]; !ok {
+		return "", options, errors.New("format must be one of png, svg")
+	}
+
+	for name, target := range map[string]*int{"size": &options.Size, "margin": &options.Margin} {
+		if value := query.Get(name); len(value) > 0 {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return "", options, fmt.Errorf("%s must be a number", name)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := query.Get("level"); len(value) > 0 {
+		options.Level = strings.ToUpper(value)
+	}
+
+	for name, target := range map[string]*color.RGBA{"fg": &options.Foreground, "bg": &options.Background} {
+		if value := query.Get(name); len(value) > 0 {
+			parsed, err := qr.ParseColor(value)
+			if err != nil {
+				return "", options, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	return format, options, options.Validate()
+}
+
+// qrETag returns entity tag of QR code, it depends only on encoded content and rendering options.
+func qrETag(content, format string, options qr.Options) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%+v", content, format, options)))
+	return fmt.Sprintf(`"qr-%x"`, sum[:12])
+}
+
+// GetUserURLQR handler renders short url of user's link as QR code in PNG or SVG. Responses have ETag
+// and are revalidated with If-None-Match, the code of link never changes.
+func GetUserURLQR(w http.ResponseWriter, r *http.Request) {
+	url, ok := getOwnURL(w, r)
+	if !ok {
+		return
+	}
+
+	format, options, err := parseQROptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tag := qrETag(url.Short, format, options)
+	header := w.Header()
+	header.Set("ETag", tag)
+	header.Set("Cache-Control", "private, no-cache")
+	header.Add("Vary", "Cookie")
+
+	if inm := r.Header.Get("If-None-Match"); len(inm) > 0 && matchesETag(inm, tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var image []byte
+	if format == "svg" {
+		image, err = qr.SVG(url.Short, options)
+	} else {
+		image, err = qr.PNG(url.Short, options)
+	}
+	if errors.Is(err, qr.ErrorTooSmall) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	header.Set("Content-Type", qrContentTypes[format])
+	header.Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.%s"`, url.ID, format))
+	header.Set("Content-Length", strconv.Itoa(len(image)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
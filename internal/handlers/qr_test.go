@@ -0,0 +1,64 @@
+package handlers_test
+
+import (
+	"context"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserURLQR(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://example.com/campaign"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/campaign")
+	require.True(t, ok)
+
+	path := "/api/user/urls/" + url.ID + "/qr"
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, path, "stranger0001", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, path+"?size=320", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+
+	img, err := png.Decode(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, 320, img.Bounds().Dx())
+
+	tag := resp.Header.Get("ETag")
+	require.NotEmpty(t, tag)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, path+"?size=320", "owner0000001", "", map[string]string{"If-None-Match": tag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+
+	// If-None-Match uses weak comparison.
+	resp, _ = testUserRequest(t, ts, http.MethodGet, path+"?size=320", "owner0000001", "", map[string]string{"If-None-Match": `"other", W/` + tag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, path+"?format=svg&level=h&margin=0&fg=%23336699&bg=fff0", "owner0000001", "",
+		map[string]string{"If-None-Match": tag})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+	assert.NotEqual(t, tag, resp.Header.Get("ETag"))
+	assert.True(t, strings.HasPrefix(body, "<svg"))
+	assert.Contains(t, body, `fill="#336699"`)
+	assert.Contains(t, body, `fill="#ffffff" fill-opacity="0"`)
+
+	for _, query := range []string{"?format=gif", "?size=10", "?size=big", "?level=X", "?margin=-1", "?fg=blue"} {
+		resp, _ = testUserRequest(t, ts, http.MethodGet, path+query, "owner0000001", "", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
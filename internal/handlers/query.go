@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policies of merging incoming query parameter into destination which already has it.
+const (
+	queryKeep     = "keep"
+	queryOverride = "override"
+	queryAppend   = "append"
+)
+
+var queryConflicts = map[string]bool{queryKeep: true, queryOverride: true, queryAppend: true}
+
+// utmParameters are names of parameters which can have per-link default values.
+var utmParameters = map[string]bool{
+	"utm_source":   true,
+	"utm_medium":   true,
+	"utm_campaign": true,
+	"utm_term":     true,
+	"utm_content":  true,
+	"utm_id":       true,
+}
+
+// maxUTMLength is limit of default UTM parameter value length in characters.
+const maxUTMLength = 256
+
+// validateQueryConflict checks conflict policy of link, empty policy means server default.
+func validateQueryConflict(policy string) error {
+	if len(policy) > 0 && !queryConflicts[policy] {
+		return fmt.Errorf("query_conflict must be one of %s, %s, %s", queryKeep, queryOverride, queryAppend)
+	}
+
+	return nil
+}
+
+// normalizeUTM checks default UTM parameters of link and trims their values, empty map means no defaults.
+func normalizeUTM(utm map[string]string) (map[string]string, error) {
+	if len(utm) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(utm))
+
+	for key, value := range utm {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !utmParameters[key] {
+			return nil, fmt.Errorf("utm: unknown parameter %q", key)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) == 0 || utf8.RuneCountInString(value) > maxUTMLength {
+			return nil, fmt.Errorf("utm: %s must be from 1 to %d characters", key, maxUTMLength)
+		}
+
+		normalized[key] = value
+	}
+
+	return normalized, nil
+}
+
+// isQueryPassthrough reports whether incoming query parameters are passed to destination of link.
+func isQueryPassthrough(url repository.URL) bool {
+	if url.QueryPassthrough != nil {
+		return *url.QueryPassthrough
+	}
+
+	return config.AppConfig.QueryPassthrough
+}
+
+// queryConflict returns conflict policy of link or server default.
+func queryConflict(url repository.URL) string {
+	for _, policy := range []string{url.QueryConflict, config.AppConfig.QueryConflict} {
+		if queryConflicts[policy] {
+			return policy
+		}
+	}
+
+	return queryKeep
+}
+
+// withQuery returns destination with incoming query parameters merged by link conflict policy if passthrough is enabled,
+// then default UTM parameters of link are added unless destination or request already has them.
+// Destination is returned untouched when nothing is added.
+func withQuery(r *http.Request, url repository.URL, destination string) string {
+	incoming := r.URL.Query()
+	passthrough := len(incoming) > 0 && isQueryPassthrough(url)
+
+	if !passthrough && len(url.UTM) == 0 {
+		return destination
+	}
+
+	target, err := neturl.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	query := target.Query()
+	changed := false
+
+	if passthrough {
+		policy := queryConflict(url)
+
+		for key, values := range incoming {
+			_, exists := query[key]
+
+			switch {
+			case !exists, policy == queryOverride:
+				query[key] = values
+			case policy == queryAppend:
+				query[key] = append(query[key], values...)
+			default:
+				continue
+			}
+
+			changed = true
+		}
+	}
+
+	for key, value := range url.UTM {
+		if _, exists := query[key]; !exists {
+			query.Set(key, value)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return destination
+	}
+
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPassthrough(t *testing.T) {
+	config.AppConfig.QueryConflict = "keep"
+	defer func() {
+		config.AppConfig.QueryPassthrough = false
+		config.AppConfig.QueryConflict = ""
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing","query_conflict":"merge"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing","utm":{"utm_foo":"bar"}}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing?lang=en","utm":{"UTM_Source":" newsletter ","utm_medium":"email"}}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	link, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/landing?lang=en")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"utm_source": "newsletter", "utm_medium": "email"}, link.UTM)
+
+	redirect := func(query string) url.Values {
+		resp, _ := testRequest(t, ts, http.MethodGet, "/"+link.ID+query, nil)
+		require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/landing", location.Path)
+
+		return location.Query()
+	}
+
+	query := redirect("?utm_source=twitter&lang=de")
+	assert.Equal(t, url.Values{"lang": {"en"}, "utm_source": {"newsletter"}, "utm_medium": {"email"}}, query)
+
+	config.AppConfig.QueryPassthrough = true
+
+	query = redirect("?utm_source=twitter&lang=de&ref=a")
+	assert.Equal(t, url.Values{"lang": {"en"}, "utm_source": {"twitter"}, "utm_medium": {"email"}, "ref": {"a"}}, query)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+link.ID, "owner0000001", `{"query_conflict":"override"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	query = redirect("?lang=de")
+	assert.Equal(t, []string{"de"}, query["lang"])
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+link.ID, "owner0000001", `{"query_conflict":"append"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	query = redirect("?lang=de")
+	assert.Equal(t, []string{"en", "de"}, query["lang"])
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+link.ID, "owner0000001", `{"query_passthrough":false,"utm":{}}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/"+link.ID+"?lang=de", nil)
+	assert.Equal(t, "https://example.com/landing?lang=en", resp.Header.Get("Location"))
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+link.ID, "owner0000001", `{"query_passthrough":null}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	query = redirect("?ref=b")
+	assert.Equal(t, []string{"b"}, query["ref"])
+}
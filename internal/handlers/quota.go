@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/LorezV/url-shorter.git/internal/quota"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+)
+
+type quotaUsageResponse struct {
+	Name      string `json:"name,omitempty"`
+	Used      int    `json:"used"`
+	Limit     *int   `json:"limit"`
+	Remaining *int   `json:"remaining"`
+}
+
+func makeQuotaUsageResponse(usage quota.Usage) quotaUsageResponse {
+	response := quotaUsageResponse{Name: usage.Name, Used: usage.Used}
+
+	if usage.Limit > 0 {
+		limit, remaining := usage.Limit, usage.Remaining()
+		response.Limit = &limit
+		response.Remaining = &remaining
+	}
+
+	return response
+}
+
+// GetUserQuota handler returns count of user's active links against personal and team limits, null limit means unlimited.
+func GetUserQuota(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	user, teams, err := quota.GetUsage(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		User  quotaUsageResponse   `json:"user"`
+		Teams []quotaUsageResponse `json:"teams"`
+	}{
+		User:  makeQuotaUsageResponse(user),
+		Teams: make([]quotaUsageResponse, len(teams)),
+	}
+
+	for index, team := range teams {
+		response.Teams[index] = makeQuotaUsageResponse(team)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// redirectTypes contains http statuses which can be used as link redirect type.
+var redirectTypes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// validateLinkOptions checks redirect type and expiration time from create requests, zero values mean defaults.
+func validateLinkOptions(redirectType int, expiresAt *time.Time) error {
+	if redirectType != 0 && !redirectTypes[redirectType] {
+		return errors.New("redirect_type must be one of 301, 302, 307, 308")
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+// validateMaxClicks checks max clicks from create requests, zero means unlimited.
+func validateMaxClicks(maxClicks int64) error {
+	if maxClicks < 0 {
+		return errors.New("max_clicks can't be negative")
+	}
+
+	return nil
+}
+
+// isExhausted reports whether url reached its max clicks.
+func isExhausted(url repository.URL) bool {
+	return url.MaxClicks > 0 && url.Clicks >= url.MaxClicks
+}
+
+// parseLinkOptions reads redirect_type and expires_at (RFC 3339) query parameters of plain text create request.
+func parseLinkOptions(r *http.Request) (int, *time.Time, error) {
+	var (
+		redirectType int
+		expiresAt    *time.Time
+		err          error
+	)
+
+	query := r.URL.Query()
+
+	if value := query.Get("redirect_type"); len(value) > 0 {
+		redirectType, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, nil, errors.New("redirect_type must be a number")
+		}
+	}
+
+	if value := query.Get("expires_at"); len(value) > 0 {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return 0, nil, errors.New("expires_at must be in RFC 3339 format")
+		}
+		expiresAt = &parsed
+	}
+
+	return redirectType, expiresAt, validateLinkOptions(redirectType, expiresAt)
+}
+
+// redirectStatus returns link redirect type or server default.
+func redirectStatus(url repository.URL) int {
+	if url.RedirectType != 0 {
+		return url.RedirectType
+	}
+
+	if redirectTypes[config.AppConfig.DefaultRedirectType] {
+		return config.AppConfig.DefaultRedirectType
+	}
+
+	return http.StatusTemporaryRedirect
+}
+
+func isExpired(url repository.URL, now time.Time) bool {
+	return url.ExpiresAt != nil && !now.Before(*url.ExpiresAt)
+}
+
+// isCacheable reports whether redirect of link is the same for every client and request, so it can be cached publicly.
+// Password protected, limited by clicks, routed by User-Agent or country and split between variants links aren't cacheable.
+func isCacheable(url repository.URL) bool {
+	return len(url.PasswordHash) == 0 && url.MaxClicks == 0 && len(url.Rules) == 0 && len(url.Variants) == 0 &&
+		len(url.GeoRules) == 0 && len(url.GeoDefault) == 0
+}
+
+// writeRedirect writes redirect to destination with cache headers. Permanent redirects of cacheable links are cached
+// publicly for config.AppConfig.RedirectCacheMaxAge but not longer than link lives or its activation window lasts.
+func writeRedirect(w http.ResponseWriter, url repository.URL, destination string) {
+	status := redirectStatus(url)
+	now := time.Now()
+	header := w.Header()
+
+	header.Add("Vary", "Accept-Encoding")
+	if len(url.Rules) > 0 {
+		header.Add("Vary", "User-Agent")
+	}
+
+	var maxAge time.Duration
+
+	if (status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect) && isCacheable(url) {
+		maxAge = config.AppConfig.RedirectCacheMaxAge
+		if url.ExpiresAt != nil && url.ExpiresAt.Sub(now) < maxAge {
+			maxAge = url.ExpiresAt.Sub(now)
+		}
+		if url.NotAfter != nil && url.NotAfter.Sub(now) < maxAge {
+			maxAge = url.NotAfter.Sub(now)
+		}
+	}
+
+	if maxAge >= time.Second {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		header.Set("Expires", now.Add(maxAge).UTC().Format(http.TimeFormat))
+	} else {
+		header.Set("Cache-Control", "private, no-cache")
+		header.Set("Expires", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+	}
+
+	header.Set("Location", destination)
+	w.WriteHeader(status)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"github.com/LorezV/url-shorter.git/internal/useragent"
+	"net/http"
+	"strings"
+)
+
+// maxRules is limit of routing rules of one url.
+const maxRules = 20
+
+// normalizeRules checks url routing rules, lowercases conditions and normalizes destinations.
+func normalizeRules(rules []repository.RoutingRule) ([]repository.RoutingRule, error) {
+	if len(rules) > maxRules {
+		return nil, fmt.Errorf("url can have at most %d rules", maxRules)
+	}
+
+	normalized := make([]repository.RoutingRule, 0, len(rules))
+
+	for index, rule := range rules {
+		rule.OS = strings.ToLower(strings.TrimSpace(rule.OS))
+		rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+
+		if len(rule.OS) == 0 && len(rule.Device) == 0 && rule.Bot == nil {
+			return nil, fmt.Errorf("rule %d: at least one of os, device and bot is required", index)
+		}
+
+		if len(rule.OS) > 0 && !useragent.IsOS(rule.OS) {
+			return nil, fmt.Errorf("rule %d: unknown os %q", index, rule.OS)
+		}
+
+		if len(rule.Device) > 0 && !useragent.IsDevice(rule.Device) {
+			return nil, fmt.Errorf("rule %d: unknown device %q", index, rule.Device)
+		}
+
+		destination, err := normalizeDestination(fmt.Sprintf("rule %d destination", index), rule.Destination)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.Destination = destination
+		normalized = append(normalized, rule)
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	return normalized, nil
+}
+
+// normalizeDestination normalizes alternative destination of url from request field and checks it against blocklist.
+func normalizeDestination(field, raw string) (string, error) {
+	destination, err := urlnorm.Normalize(raw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(destination); blocked {
+		return "", fmt.Errorf("%s: %w", field, screening.ErrorBlocked)
+	}
+
+	return destination, nil
+}
+
+// destinationErrorStatus returns response status for error of destination checks, blocked destinations are forbidden.
+func destinationErrorStatus(err error) int {
+	if errors.Is(err, screening.ErrorBlocked) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusBadRequest
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"context"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingRules(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/app","rules":[{"os":"symbian","destination":"https://example.com/nokia"}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/app","rules":[{"destination":"https://example.com/any"}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://example.com/app","rules":[
+		{"bot":true,"destination":"https://example.com/app/preview"},
+		{"os":"iOS","destination":"https://apps.apple.com/app/id123456"},
+		{"os":"android","device":"mobile","destination":"https://play.google.com/store/apps/details?id=com.example"}
+	]}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/app")
+	require.True(t, ok)
+	require.Len(t, url.Rules, 3)
+	assert.Equal(t, "ios", url.Rules[1].OS)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		location  string
+	}{
+		{
+			name:      "iPhone opens App Store.",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			location:  "https://apps.apple.com/app/id123456",
+		},
+		{
+			name:      "Android phone opens Google Play.",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			location:  "https://play.google.com/store/apps/details?id=com.example",
+		},
+		{
+			name:      "Android tablet opens web page.",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			location:  "https://example.com/app",
+		},
+		{
+			name:      "Bot matches first rule.",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			location:  "https://example.com/app/preview",
+		},
+		{
+			name:      "Desktop opens web page.",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			location:  "https://example.com/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", map[string]string{"User-Agent": tt.userAgent})
+
+			assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+			assert.Equal(t, tt.location, resp.Header.Get("Location"))
+			assert.Contains(t, resp.Header.Values("Vary"), "User-Agent")
+		})
+	}
+
+	resp, body := testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"rules":[]}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body, "rules")
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", map[string]string{"User-Agent": tests[0].userAgent})
+	assert.Equal(t, "https://example.com/app", resp.Header.Get("Location"))
+}
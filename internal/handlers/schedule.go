@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// States of link activation window.
+const (
+	scheduleActive = iota
+	scheduleUpcoming
+	scheduleEnded
+)
+
+// validateSchedule checks activation window of link, nil bounds mean window is open from that side.
+func validateSchedule(notBefore, notAfter *time.Time) error {
+	if notAfter != nil && !notAfter.After(time.Now()) {
+		return errors.New("not_after must be in the future")
+	}
+
+	if notBefore != nil && notAfter != nil && !notAfter.After(*notBefore) {
+		return errors.New("not_after must be after not_before")
+	}
+
+	return nil
+}
+
+// normalizeFallbackURL checks url which is opened outside of link activation window, empty value means no fallback.
+func normalizeFallbackURL(raw string) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	return normalizeDestination("fallback_url", raw)
+}
+
+// scheduleState reports whether link is active at the moment or its window hasn't started or has ended.
+func scheduleState(url repository.URL, now time.Time) int {
+	if url.NotBefore != nil && now.Before(*url.NotBefore) {
+		return scheduleUpcoming
+	}
+
+	if url.NotAfter != nil && !now.Before(*url.NotAfter) {
+		return scheduleEnded
+	}
+
+	return scheduleActive
+}
+
+// comingSoonStatus returns configured status of coming soon page or 503.
+func comingSoonStatus() int {
+	status := config.AppConfig.ComingSoonStatus
+	if len(http.StatusText(status)) == 0 {
+		return http.StatusServiceUnavailable
+	}
+
+	return status
+}
+
+// writeOutsideSchedule redirects to fallback url of link outside its activation window, links without fallback
+// answer with coming soon page before window and 410 after it. Response isn't cached because it changes with time.
+func writeOutsideSchedule(w http.ResponseWriter, r *http.Request, url repository.URL, state int, now time.Time) {
+	header := w.Header()
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("Expires", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+
+	if len(url.FallbackURL) > 0 {
+		if _, blocked := screening.GlobalBlocklist.Check(url.FallbackURL); !blocked {
+			header.Set("Location", withQuery(r, url, url.FallbackURL))
+			w.WriteHeader(http.StatusTemporaryRedirect)
+			return
+		}
+	}
+
+	data := struct {
+		Ended     bool
+		NotBefore *time.Time
+		Message   string
+	}{Ended: state == scheduleEnded}
+
+	if data.Ended {
+		renderPage(w, http.StatusGone, scheduleTemplate, data)
+		return
+	}
+
+	data.NotBefore = url.NotBefore
+	data.Message = config.AppConfig.ComingSoonMessage
+	header.Set("Retry-After", strconv.Itoa(int(math.Ceil(url.NotBefore.Sub(now).Seconds()))))
+	renderPage(w, comingSoonStatus(), scheduleTemplate, data)
+}
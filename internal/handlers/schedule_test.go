@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledURL(t *testing.T) {
+	config.AppConfig.ComingSoonMessage = "Sale starts soon!"
+	defer func() { config.AppConfig.ComingSoonMessage = "" }()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		url        repository2.URL
+		statusCode int
+		location   string
+		body       string
+	}{
+		{
+			name:       "Link before window shows coming soon page.",
+			url:        repository2.URL{ID: "soon01", Original: "https://example.com/sale", NotBefore: &future},
+			statusCode: http.StatusServiceUnavailable,
+			body:       "Sale starts soon!",
+		},
+		{
+			name:       "Link before window redirects to fallback.",
+			url:        repository2.URL{ID: "soon02", Original: "https://example.com/sale", NotBefore: &future, FallbackURL: "https://example.com/teaser"},
+			statusCode: http.StatusTemporaryRedirect,
+			location:   "https://example.com/teaser",
+		},
+		{
+			name:       "Link after window is gone.",
+			url:        repository2.URL{ID: "over01", Original: "https://example.com/sale", NotAfter: &past},
+			statusCode: http.StatusGone,
+			body:       "no longer active",
+		},
+		{
+			name:       "Link after window redirects to fallback.",
+			url:        repository2.URL{ID: "over02", Original: "https://example.com/sale", NotAfter: &past, FallbackURL: "https://example.com/archive"},
+			statusCode: http.StatusTemporaryRedirect,
+			location:   "https://example.com/archive",
+		},
+		{
+			name:       "Link inside window redirects to destination.",
+			url:        repository2.URL{ID: "live01", Original: "https://example.com/sale", NotBefore: &past, NotAfter: &future, FallbackURL: "https://example.com/archive"},
+			statusCode: http.StatusTemporaryRedirect,
+			location:   "https://example.com/sale",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository2.GlobalRepository = repository2.MakeMemoryRepository()
+			repository2.GlobalRepository.Insert(context.Background(), tt.url)
+
+			ts := makeLinksServer()
+			defer ts.Close()
+
+			resp, body := testRequest(t, ts, http.MethodGet, "/"+tt.url.ID, nil)
+
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+			assert.Equal(t, tt.location, resp.Header.Get("Location"))
+			assert.Contains(t, body, tt.body)
+			assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+		})
+	}
+
+	t.Run("Coming soon page has Retry-After header.", func(t *testing.T) {
+		repository2.GlobalRepository = repository2.MakeMemoryRepository()
+		repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "soon03", Original: "https://example.com/sale", NotBefore: &future})
+
+		ts := makeLinksServer()
+		defer ts.Close()
+
+		resp, _ := testRequest(t, ts, http.MethodGet, "/soon03", nil)
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, time.Hour.Seconds(), retryAfter, 5)
+	})
+}
+
+func TestScheduleOptions(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	notBefore := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	notAfter := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		fmt.Sprintf(`{"url":"https://example.com/sale","not_before":%q,"not_after":%q}`, notAfter, notBefore), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/sale","fallback_url":"ftp://example.com/teaser"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		fmt.Sprintf(`{"url":"https://example.com/sale","not_before":%q,"not_after":%q,"fallback_url":"https://example.com/teaser"}`, notBefore, notAfter), nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/sale")
+	require.True(t, ok)
+	require.NotNil(t, url.NotBefore)
+	assert.Equal(t, "https://example.com/teaser", url.FallbackURL)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/"+url.ID, nil)
+	assert.Equal(t, "https://example.com/teaser", resp.Header.Get("Location"))
+
+	resp, body := testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"not_before":null,"fallback_url":""}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body, "not_before")
+	assert.NotContains(t, body, "fallback_url")
+	assert.Contains(t, body, "not_after")
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/"+url.ID, nil)
+	assert.Equal(t, "https://example.com/sale", resp.Header.Get("Location"))
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"not_after":"2000-01-01T00:00:00Z"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
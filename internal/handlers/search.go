@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/search"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"strconv"
+)
+
+type searchResultResponse struct {
+	userURLResponse
+	Rank float64 `json:"rank"`
+}
+
+// SearchUserUrls handler searches user's urls by alias, title, tags and original url from "q" query parameter.
+// Results are ordered by rank, count is limited by "limit" query parameter.
+func SearchUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+	query := r.URL.Query().Get("q")
+
+	if len(search.Tokenize(query)) == 0 {
+		http.Error(w, "The query parameter q must contain words to search", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+
+	if value := r.URL.Query().Get("limit"); len(value) > 0 {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			http.Error(w, "The query parameter limit must be number from 1 to 100", http.StatusBadRequest)
+			return
+		}
+
+		limit = parsed
+	}
+
+	hits, err := repository.GlobalRepository.Search(r.Context(), userID, query, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(hits) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	v := make([]searchResultResponse, len(hits))
+	for index, hit := range hits {
+		v[index] = searchResultResponse{userURLResponse: makeUserURLResponse(hit.URL), Rank: hit.Rank}
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchUserUrls(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "q3deck", Original: "https://docs.example.com/d/8f2a", Short: "http://127.0.0.1:8080/q3deck", UserID: "owner0000001", Title: "Q3 pricing doc"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "blog01", Original: "https://blog.example.com/pricing-changes-q3", Short: "http://127.0.0.1:8080/blog01", UserID: "owner0000001"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "other1", Original: "https://example.com/q3-pricing", Short: "http://127.0.0.1:8080/other1", UserID: "stranger0001"})
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "misc01", Original: "https://example.com/about", Short: "http://127.0.0.1:8080/misc01", UserID: "owner0000001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPut, "/api/user/urls/misc01/tags", "owner0000001", `["Pricing","q3"]`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/search?q=q3+pricing", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []struct {
+		ID   string  `json:"id"`
+		Rank float64 `json:"rank"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &results))
+	require.Len(t, results, 3)
+	assert.Equal(t, "q3deck", results[0].ID)
+	assert.Equal(t, "misc01", results[1].ID)
+	assert.Equal(t, "blog01", results[2].ID)
+	assert.Greater(t, results[1].Rank, results[2].Rank)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/search?q=blog01", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"id":"blog01"`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/search?q=nothing", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/search?q=https://", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/quota"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"io"
+	"net/http"
+)
+
+// streamChunkSize is count of urls inserted in one transaction by streaming batch.
+const streamChunkSize = 100
+
+// maxStreamLineSize is limit of one line size in streaming batch.
+const maxStreamLineSize = 1 << 20
+
+// streamResult is a line of streaming batch response.
+type streamResult struct {
+	Line          int    `json:"line,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Error         string `json:"error,omitempty"`
+	*urlMetadata
+}
+
+// enableFullDuplex allows to read request body after response is started. Server closes unread body of HTTP/1 request
+// once response is flushed unless full duplex is enabled, HTTP/2 is always full duplex. Wrapped writers are unwrapped
+// like http.ResponseController does.
+func enableFullDuplex(w http.ResponseWriter) bool {
+	for {
+		switch writer := w.(type) {
+		case interface{ EnableFullDuplex() error }:
+			return writer.EnableFullDuplex() == nil
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// BatchURLStream handler creates urls from NDJSON body line by line. Urls are inserted by chunks in separate
+// transactions and result lines are flushed for every chunk once it is committed, so big batches are neither held in
+// memory nor in one long transaction. Invalid lines are reported immediately and don't stop the stream.
+func BatchURLStream(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	// Without full duplex results are buffered until the body is read, otherwise the rest of body would be lost.
+	var out io.Writer = w
+	var buffer *bytes.Buffer
+	if r.ProtoMajor < 2 && !enableFullDuplex(w) {
+		buffer = &bytes.Buffer{}
+		out = buffer
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(out)
+
+	flush := func() {
+		if flusher, ok := w.(http.Flusher); ok && buffer == nil {
+			flusher.Flush()
+		}
+	}
+
+	defer func() {
+		if buffer != nil {
+			w.Write(buffer.Bytes())
+		}
+	}()
+
+	var (
+		urls    []repository.URL
+		results []streamResult
+		limits  = quota.Limits(userID)
+	)
+
+	commit := func() bool {
+		if len(urls) == 0 {
+			return true
+		}
+
+		saved, err := repository.GlobalRepository.InsertMany(r.Context(), urls, limits...)
+		for index := range results {
+			if err != nil {
+				results[index].Error = err.Error()
+			} else if len(saved[index].ID) == 0 {
+				results[index].Error = quota.ErrorQuotaExceeded.Error()
+			} else {
+				metadata := makeURLMetadata(saved[index])
+				results[index].ShortURL = saved[index].Short
+				results[index].urlMetadata = &metadata
+			}
+
+			encoder.Encode(results[index])
+		}
+
+		if err == nil {
+			fetchPages(saved...)
+		}
+
+		urls, results = urls[:0], results[:0]
+		flush()
+
+		return err == nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var element batchElement
+		if err := json.Unmarshal(text, &element); err != nil {
+			encoder.Encode(streamResult{Line: line, Error: err.Error()})
+			continue
+		}
+
+		url, invalid, err := makeBatchURL(r, userID, element)
+		if err != nil {
+			commit()
+			encoder.Encode(streamResult{Line: line, CorrelationID: element.CorrelationID, Error: err.Error()})
+			return
+		}
+
+		if invalid != nil {
+			encoder.Encode(streamResult{Line: line, CorrelationID: element.CorrelationID, Error: invalid.Error()})
+			continue
+		}
+
+		urls = append(urls, url)
+		results = append(results, streamResult{Line: line, CorrelationID: element.CorrelationID})
+
+		if len(urls) == streamChunkSize && !commit() {
+			return
+		}
+	}
+
+	if !commit() {
+		return
+	}
+
+	if err := scanner.Err(); err != nil {
+		encoder.Encode(streamResult{Line: line + 1, Error: err.Error()})
+	}
+}
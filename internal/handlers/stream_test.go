@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamLine struct {
+	Line          int      `json:"line"`
+	CorrelationID string   `json:"correlation_id"`
+	ShortURL      string   `json:"short_url"`
+	Error         string   `json:"error"`
+	Tags          []string `json:"tags"`
+}
+
+func TestBatchURLStream(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "exist1", Original: "https://example.com/exist", Short: "http://127.0.0.1:8080/exist1", UserID: "stranger0001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	var body strings.Builder
+	for index := 0; index < 150; index++ {
+		fmt.Fprintf(&body, `{"correlation_id":"%d","original_url":"https://example.com/%d","tags":["bulk"]}`+"\n", index, index)
+	}
+	body.WriteString("\n{broken\n")
+	body.WriteString(`{"correlation_id":"bad","original_url":"not a url"}` + "\n")
+	body.WriteString(`{"correlation_id":"exist","original_url":"https://example.com/exist"}`)
+
+	resp, respBody := testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch/stream", "owner0000001", body.String(), map[string]string{"Content-Type": "application/x-ndjson"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, respBody)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	results := map[string]streamLine{}
+	var failed []streamLine
+	for _, text := range strings.Split(strings.TrimSpace(respBody), "\n") {
+		var line streamLine
+		require.NoError(t, json.Unmarshal([]byte(text), &line))
+		if line.Error != "" {
+			failed = append(failed, line)
+			continue
+		}
+
+		results[line.CorrelationID] = line
+	}
+
+	assert.Len(t, results, 151)
+	assert.Equal(t, []string{"bulk"}, results["42"].Tags)
+	assert.Equal(t, 43, results["42"].Line)
+	assert.Equal(t, "http://127.0.0.1:8080/exist1", results["exist"].ShortURL)
+
+	require.Len(t, failed, 2)
+	assert.Equal(t, 152, failed[0].Line)
+	assert.Equal(t, "bad", failed[1].CorrelationID)
+
+	urls, _, err := repository2.GlobalRepository.GetAllByUser(context.Background(), "owner0000001", repository2.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, urls, 150)
+}
+
+func TestBatchURLStreamCommitsChunks(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	bodyReader, bodyWriter := io.Pipe()
+	req, err := makeRequest(ts, http.MethodPost, "/api/shorten/batch/stream", bodyReader)
+	require.NoError(t, err)
+	req.AddCookie(userCookie("owner0000001"))
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := makeClient().Do(req)
+		if err == nil {
+			responses <- resp
+		}
+	}()
+
+	for index := 0; index < 100; index++ {
+		fmt.Fprintf(bodyWriter, `{"correlation_id":"%d","original_url":"https://example.com/%d"}`+"\n", index, index)
+	}
+
+	// First chunk must be answered while request body is still open.
+	var resp *http.Response
+	select {
+	case resp = <-responses:
+	case <-time.After(5 * time.Second):
+		t.Fatal("response is not started before end of body")
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for index := 0; index < 100; index++ {
+		select {
+		case line := <-lines:
+			assert.Contains(t, line, fmt.Sprintf(`"correlation_id":"%d"`, index))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("result %d is not streamed before end of body", index)
+		}
+	}
+
+	fmt.Fprintln(bodyWriter, `{"correlation_id":"last","original_url":"https://example.com/last"}`)
+	bodyWriter.Close()
+
+	var rest []string
+	for line := range lines {
+		rest = append(rest, line)
+	}
+
+	require.Len(t, rest, 1)
+	assert.Contains(t, rest[0], `"correlation_id":"last"`)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits of tags count per url and tag length in characters.
+const (
+	maxTagsPerURL = 20
+	maxTagLength  = 64
+)
+
+// normalizeTag lowercases and trims tag, tags can't be empty, contain commas or control characters.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	if len(tag) == 0 {
+		return "", errors.New("tag can't be empty")
+	}
+
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("tag must be at most %d characters", maxTagLength)
+	}
+
+	if strings.IndexFunc(tag, func(r rune) bool { return r == ',' || unicode.IsControl(r) }) >= 0 {
+		return "", errors.New("tag can't contain commas or control characters")
+	}
+
+	return tag, nil
+}
+
+// normalizeTags normalizes, deduplicates and sorts tags of url.
+func normalizeTags(tags []string) ([]string, error) {
+	unique := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if !unique[tag] {
+			unique[tag] = true
+			result = append(result, tag)
+		}
+	}
+
+	if len(result) > maxTagsPerURL {
+		return nil, fmt.Errorf("url can have at most %d tags", maxTagsPerURL)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// tagParam returns normalized tag from path.
+func tagParam(r *http.Request) (string, error) {
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		return "", err
+	}
+
+	return normalizeTag(tag)
+}
+
+// GetUserTags handler returns user's tags with count of urls marked with each of them.
+func GetUserTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	tags, err := repository.GlobalRepository.GetTagsByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// RenameUserTag handler renames tag on all user's urls, new name is taken from "name" field of body.
+func RenameUserTag(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	var data struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := tagParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := normalizeTag(data.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	count, err := repository.GlobalRepository.RenameTag(r.Context(), userID, from, to)
+	writeTagResult(w, count, err)
+}
+
+// DeleteUserTag handler removes tag from all user's urls.
+func DeleteUserTag(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	tag, err := tagParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	count, err := repository.GlobalRepository.DeleteTag(r.Context(), userID, tag)
+	writeTagResult(w, count, err)
+}
+
+func writeTagResult(w http.ResponseWriter, count int, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		http.Error(w, "Tag not found!", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Updated int `json:"updated"`
+	}{Updated: count})
+}
+
+// SetUserURLTags handler replaces tags of user's url with tags array from body.
+func SetUserURLTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	var tags []string
+
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	url, err := repository.GlobalRepository.SetTags(r.Context(), chi.URLParam(r, "id"), userID, tags)
+	writeUserURLResult(w, url, err)
+}
+
+// writeUserURLResult writes changed user's url or error of change to response.
+func writeUserURLResult(w http.ResponseWriter, url repository.URL, err error) {
+	if err != nil {
+		if errors.Is(err, repository.ErrorURLNotFound) || errors.Is(err, repository.ErrorURLForbidden) {
+			http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag(url))
+	writeJSON(w, http.StatusOK, makeUserURLResponse(url))
+}
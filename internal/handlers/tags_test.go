@@ -0,0 +1,62 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTags(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "tagged1", Original: "https://example.com/1", Short: "http://127.0.0.1:8080/tagged1", UserID: "owner0000001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, body := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"https://example.com/2","tags":["Promo"," autumn ","promo"]}`, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	resp, body = testUserRequest(t, ts, http.MethodPut, "/api/user/urls/tagged1/tags", "owner0000001", `["promo","Docs"]`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"tags":["docs","promo"]`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPut, "/api/user/urls/tagged1/tags", "stranger0001", `["spam"]`, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPut, "/api/user/urls/tagged1/tags", "owner0000001", `["a,b"]`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/tags", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `[{"name":"autumn","count":1},{"name":"docs","count":1},{"name":"promo","count":2}]`, body)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls?tag=docs", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var urls []struct {
+		ShortURL string   `json:"short_url"`
+		Tags     []string `json:"tags"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "http://127.0.0.1:8080/tagged1", urls[0].ShortURL)
+
+	resp, body = testUserRequest(t, ts, http.MethodPatch, "/api/user/tags/promo", "owner0000001", `{"name":"sale"}`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"updated":2}`, body)
+
+	resp, body = testUserRequest(t, ts, http.MethodDelete, "/api/user/tags/docs", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"updated":1}`, body)
+
+	resp, _ = testUserRequest(t, ts, http.MethodDelete, "/api/user/tags/docs", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/tags", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `[{"name":"autumn","count":1},{"name":"sale","count":2}]`, body)
+}
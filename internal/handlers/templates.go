@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var disabledTemplate = template.Must(template.New("disabled").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link unavailable</title></head>
+<body>
+<h1>This link is no longer available</h1>
+{{if .}}<p>Reason: {{.}}</p>{{end}}
+</body>
+</html>`))
+
+var blockedTemplate = template.Must(template.New("blocked").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Warning: suspicious link</title></head>
+<body>
+<h1>Warning: this link may be harmful</h1>
+<p>The destination of this short link matches our list of phishing and malicious sites.</p>
+<p>Destination: <code>{{.}}</code></p>
+<p><a href="{{.}}" rel="noopener noreferrer nofollow">I understand the risk, continue anyway</a></p>
+</body>
+</html>`))
+
+var unlockTemplate = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Protected link</title></head>
+<body>
+<h1>This link is protected by password</h1>
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<form method="post" action="/{{.ID}}">
+<input type="password" name="password" autofocus required>
+<button type="submit">Open link</button>
+</form>
+</body>
+</html>`))
+
+var scheduleTemplate = template.Must(template.New("schedule").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>{{if .Ended}}Link is no longer active{{else}}Coming soon{{end}}</title></head>
+<body>
+{{if .Ended}}<h1>This link is no longer active</h1>{{else}}<h1>Coming soon</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .NotBefore}}<p>This link will be available from {{.NotBefore.UTC.Format "2006-01-02 15:04 MST"}}.</p>{{end}}{{end}}
+</body>
+</html>`))
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.Short}}">
+<meta property="og:title" content="{{.Title}}">
+{{if .Description}}<meta property="og:description" content="{{.Description}}">
+{{else if .Destination}}<meta property="og:description" content="{{.Destination}}">
+{{end}}<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content="{{.Title}}">
+{{if .Description}}<meta name="twitter:description" content="{{.Description}}">
+{{end}}</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Protected}}<p>The destination of this link is protected by password.</p>
+{{else}}<p>This short link leads to: <code>{{.Destination}}</code></p>
+{{if .Blocked}}<p>Warning: this destination matches our list of phishing and malicious sites.</p>{{end}}{{end}}
+{{if .Inactive}}<p>This link is not active right now.</p>{{end}}
+<p>Created {{.CreatedAt.UTC.Format "2006-01-02"}}, followed {{.Clicks}} times.</p>
+{{if not .Blocked}}<p><a href="{{.Short}}" rel="nofollow">Continue</a></p>{{end}}
+</body>
+</html>`))
+
+// renderPage writes html page from template with given status code.
+func renderPage(w http.ResponseWriter, status int, tmpl *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	tmpl.Execute(w, data)
+}
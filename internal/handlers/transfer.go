@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/quota"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/screening"
+	"github.com/LorezV/url-shorter.git/internal/urlnorm"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of imported rows.
+const (
+	importCreated   = "created"
+	importDuplicate = "duplicate"
+	importFailed    = "failed"
+)
+
+// maxImportRows is limit of rows in one import request.
+const maxImportRows = 100000
+
+// exportPageSize is count of urls read from repository at once while export is streamed.
+const exportPageSize = 500
+
+// aliasPattern is allowed form of custom url id.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,12}$`)
+
+// reservedAliases are first path segments of service routes which can't be url ids.
+var reservedAliases = map[string]bool{"api": true, "ping": true, "debug": true}
+
+// importColumns maps accepted csv header names to import fields.
+var importColumns = map[string]string{
+	"original_url": "original_url",
+	"original":     "original_url",
+	"url":          "original_url",
+	"alias":        "alias",
+	"id":           "alias",
+	"tags":         "tags",
+	"expires_at":   "expires_at",
+	"expiry":       "expires_at",
+}
+
+// exportHeader is header of csv export, it can be imported back.
+var exportHeader = []string{"original_url", "alias", "tags", "expires_at", "short_url", "title", "clicks", "created_at"}
+
+// exportContentTypes maps supported export formats to their content types.
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// importRow is result of import of one csv row.
+type importRow struct {
+	Line     int    `json:"line"`
+	Status   string `json:"status"`
+	ID       string `json:"id,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// importSummary is last line of import response, Error is set when import is stopped before end of body.
+type importSummary struct {
+	Created    int    `json:"created"`
+	Duplicates int    `json:"duplicates"`
+	Failed     int    `json:"failed"`
+	Error      string `json:"error,omitempty"`
+}
+
+// normalizeAlias checks custom url id.
+func normalizeAlias(alias string) (string, error) {
+	alias = strings.TrimSpace(alias)
+
+	if !aliasPattern.MatchString(alias) {
+		return "", errors.New("alias must be 3 to 12 letters, digits, - or _")
+	}
+
+	if reservedAliases[strings.ToLower(alias)] {
+		return "", fmt.Errorf("alias %q is reserved", alias)
+	}
+
+	return alias, nil
+}
+
+// parseImportHeader returns column index of every import field, original_url column is required.
+func parseImportHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := importColumns[name]; ok {
+			if _, duplicate := columns[field]; duplicate {
+				return nil, fmt.Errorf("column %s is given twice", field)
+			}
+			columns[field] = index
+		}
+	}
+
+	if _, ok := columns["original_url"]; !ok {
+		return nil, errors.New("csv header must contain original_url column")
+	}
+
+	return columns, nil
+}
+
+// makeImportedURL validates csv record and returns url to insert.
+func makeImportedURL(r *http.Request, userID string, record []string, columns map[string]int) (repository.URL, error) {
+	field := func(name string) string {
+		if index, ok := columns[name]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	if len(field("original_url")) == 0 {
+		return repository.URL{}, errors.New("original_url can't be empty")
+	}
+
+	original, err := urlnorm.Normalize(field("original_url"))
+	if err != nil {
+		return repository.URL{}, err
+	}
+
+	if _, blocked := screening.GlobalBlocklist.Check(original); blocked {
+		return repository.URL{}, screening.ErrorBlocked
+	}
+
+	var expiresAt *time.Time
+	if value := field("expires_at"); len(value) > 0 {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return repository.URL{}, errors.New("expires_at must be in RFC 3339 format")
+		}
+		expiresAt = &parsed
+	}
+
+	if err := validateLinkOptions(0, expiresAt); err != nil {
+		return repository.URL{}, err
+	}
+
+	var tags []string
+	if value := field("tags"); len(value) > 0 {
+		tags, err = normalizeTags(strings.Split(value, ","))
+		if err != nil {
+			return repository.URL{}, err
+		}
+	}
+
+	url, err := makeURL(r, original, userID)
+	if err != nil {
+		return url, err
+	}
+
+	if value := field("alias"); len(value) > 0 {
+		alias, err := normalizeAlias(value)
+		if err != nil {
+			return url, err
+		}
+
+		url.ID = alias
+		url.Short = fmt.Sprintf("%s/%s", config.AppConfig.BaseURL, alias)
+	}
+
+	url.ExpiresAt = expiresAt
+	url.Tags = tags
+
+	return url, nil
+}
+
+// ImportUserUrls handler creates user's urls from csv body read row by row. First row is header with original_url
+// column and optional alias, tags (comma separated) and expires_at (RFC 3339) columns, other columns are ignored.
+// Result of every row is streamed as NDJSON line once the row is handled: created, duplicate of existing url with
+// the same destination, or failed with error. Last line is summary, with error when import is stopped early.
+func ImportUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	reader := csv.NewReader(r.Body)
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("can't handle empty body")
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Without full duplex results are buffered until the body is read, otherwise the rest of body would be lost.
+	var out io.Writer = w
+	var buffer *bytes.Buffer
+	if r.ProtoMajor < 2 && !enableFullDuplex(w) {
+		buffer = &bytes.Buffer{}
+		out = buffer
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(out)
+
+	var summary importSummary
+	defer func() {
+		encoder.Encode(summary)
+		if buffer != nil {
+			w.Write(buffer.Bytes())
+		}
+	}()
+
+	report := func(row importRow) {
+		switch row.Status {
+		case importCreated:
+			summary.Created++
+		case importDuplicate:
+			summary.Duplicates++
+		default:
+			summary.Failed++
+		}
+
+		encoder.Encode(row)
+		if flusher, ok := w.(http.Flusher); ok && buffer == nil {
+			flusher.Flush()
+		}
+	}
+
+	limits := quota.Limits(userID)
+	rows := 0
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			report(importRow{Line: parseError.StartLine, Status: importFailed, Error: parseError.Err.Error()})
+			break
+		}
+		if err != nil {
+			summary.Error = err.Error()
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{Line: line, Status: importFailed}
+
+		if rows == maxImportRows {
+			summary.Error = fmt.Sprintf("import is limited to %d rows", maxImportRows)
+			return
+		}
+		rows++
+
+		url, err := makeImportedURL(r, userID, record, columns)
+		if err != nil {
+			row.Error = err.Error()
+			report(row)
+			continue
+		}
+
+		saved, err := repository.GlobalRepository.Insert(r.Context(), url, limits...)
+		switch {
+		case errors.Is(err, quota.ErrorQuotaExceeded):
+			row.Error = err.Error()
+		case errors.Is(err, repository.ErrorURLDuplicate) && saved.Original != url.Original:
+			row.Error = fmt.Sprintf("alias %q is already taken", url.ID)
+		case errors.Is(err, repository.ErrorURLDuplicate):
+			row.Status, row.ID, row.ShortURL = importDuplicate, saved.ID, saved.Short
+		case err != nil:
+			// Rows before are already saved, so import is stopped with their results instead of bare error.
+			row.Error = err.Error()
+			report(row)
+			summary.Error = "import is stopped: " + err.Error()
+			return
+		default:
+			row.Status, row.ID, row.ShortURL = importCreated, saved.ID, saved.Short
+			fetchPages(saved)
+		}
+
+		report(row)
+	}
+}
+
+// ExportUserUrls handler streams all user's urls matching listing filters as csv, json array or ndjson by format
+// query parameter. Urls are read from repository page by page, csv export can be imported back.
+func ExportUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = "csv"
+	}
+
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		http.Error(w, "the query parameter format must be csv, json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	options, err := parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.Limit = exportPageSize
+
+	// First page is read before response is started, so repository errors can still be reported with status.
+	urls, next, err := repository.GlobalRepository.GetAllByUser(r.Context(), userID, options)
+	if err != nil {
+		if errors.Is(err, repository.ErrorInvalidCursor) {
+			http.Error(w, "The query parameter cursor is invalid", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Can't get urls from repository.", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="urls.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+	count := 0
+
+	switch format {
+	case "csv":
+		csvWriter.Write(exportHeader)
+	case "json":
+		io.WriteString(w, "[")
+	}
+
+	for {
+		for _, url := range urls {
+			switch format {
+			case "csv":
+				record := []string{url.Original, url.ID, strings.Join(url.Tags, ","), "", url.Short, url.Title,
+					strconv.FormatInt(url.Clicks, 10), url.CreatedAt.UTC().Format(time.RFC3339)}
+				if url.ExpiresAt != nil {
+					record[3] = url.ExpiresAt.UTC().Format(time.RFC3339)
+				}
+				csvWriter.Write(record)
+			case "json":
+				if count > 0 {
+					io.WriteString(w, ",")
+				}
+				encoder.Encode(makeUserURLResponse(url))
+			case "ndjson":
+				encoder.Encode(makeUserURLResponse(url))
+			}
+			count++
+		}
+
+		csvWriter.Flush()
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if len(next) == 0 || r.Context().Err() != nil {
+			break
+		}
+
+		options.Cursor = next
+		urls, next, err = repository.GlobalRepository.GetAllByUser(r.Context(), userID, options)
+		if err != nil {
+			log.Printf("Can't export urls of %s: %v", userID, err)
+			return
+		}
+	}
+
+	if format == "json" {
+		io.WriteString(w, "]\n")
+	}
+}
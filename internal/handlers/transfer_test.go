@@ -0,0 +1,204 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type importLine struct {
+	Line     int    `json:"line"`
+	Status   string `json:"status"`
+	ID       string `json:"id"`
+	ShortURL string `json:"short_url"`
+	Error    string `json:"error"`
+}
+
+type importSummary struct {
+	Created    int    `json:"created"`
+	Duplicates int    `json:"duplicates"`
+	Failed     int    `json:"failed"`
+	Error      string `json:"error"`
+}
+
+// parseImport splits NDJSON import response into row results and summary from the last line.
+func parseImport(t *testing.T, body string) ([]importLine, importSummary) {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+
+	var summary importSummary
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+
+	rows := make([]importLine, len(lines)-1)
+	for index, text := range lines[:len(lines)-1] {
+		require.NoError(t, json.Unmarshal([]byte(text), &rows[index]))
+	}
+
+	return rows, summary
+}
+
+// failingRepository fails inserts after given count of successful ones.
+type failingRepository struct {
+	repository2.Repository
+	inserts int
+}
+
+func (r *failingRepository) Insert(ctx context.Context, url repository2.URL, limits ...repository2.Limit) (repository2.URL, error) {
+	if r.inserts == 0 {
+		return repository2.URL{}, errors.New("connection is lost")
+	}
+	r.inserts--
+
+	return r.Repository.Insert(ctx, url, limits...)
+}
+
+func TestImportUserUrls(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "taken1", Original: "https://example.com/taken", Short: "http://127.0.0.1:8080/taken1", UserID: "stranger0001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/user/urls/import", "owner0000001", "alias,tags\nabc,x\n", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := "\ufeffURL,Alias,Tags,Expires_At,Legacy Clicks\n" +
+		"https://example.com/docs,docs,\"Docs, Guides\"," + expires + ",10\n" +
+		"https://example.com/blog,,,,3\n" +
+		"https://example.com/docs,,,,0\n" +
+		"https://example.com/other,taken1,,,0\n" +
+		"not a url,,,,0\n" +
+		"https://example.com/late,,,yesterday,0\n" +
+		"https://example.com/api,api,,,0\n"
+
+	resp, respBody := testUserRequest(t, ts, http.MethodPost, "/api/user/urls/import", "owner0000001", body, map[string]string{"Content-Type": "text/csv"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, respBody)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	rows, summary := parseImport(t, respBody)
+	assert.Equal(t, importSummary{Created: 2, Duplicates: 1, Failed: 4}, summary)
+	require.Len(t, rows, 7)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "created", rows[0].Status)
+	assert.Equal(t, "docs", rows[0].ID)
+	assert.True(t, strings.HasSuffix(rows[0].ShortURL, "/docs"))
+	assert.Equal(t, "created", rows[1].Status)
+	assert.Equal(t, "duplicate", rows[2].Status)
+	assert.Equal(t, "docs", rows[2].ID)
+	assert.Equal(t, "failed", rows[3].Status)
+	assert.Contains(t, rows[3].Error, "already taken")
+	for _, row := range rows[3:] {
+		assert.Equal(t, "failed", row.Status, row.Line)
+		assert.NotEmpty(t, row.Error, row.Line)
+	}
+
+	docs, ok := repository2.GlobalRepository.Get(context.Background(), "docs")
+	require.True(t, ok)
+	assert.Equal(t, "owner0000001", docs.UserID)
+	assert.Equal(t, []string{"docs", "guides"}, docs.Tags)
+	require.NotNil(t, docs.ExpiresAt)
+
+	resp, _ = testRequest(t, ts, http.MethodGet, "/docs", nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, "https://example.com/docs", resp.Header.Get("Location"))
+
+	resp, respBody = testUserRequest(t, ts, http.MethodPost, "/api/user/urls/import", "owner0000001", "original_url\n\"https://example.com/broken\n", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, summary = parseImport(t, respBody)
+	assert.Equal(t, 1, summary.Failed)
+}
+
+func TestImportUserUrlsStoreFailure(t *testing.T) {
+	repository2.GlobalRepository = &failingRepository{Repository: repository2.MakeMemoryRepository(), inserts: 2}
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	body := "original_url\nhttps://example.com/1\nhttps://example.com/2\nhttps://example.com/3\nhttps://example.com/4\n"
+	resp, respBody := testUserRequest(t, ts, http.MethodPost, "/api/user/urls/import", "owner0000001", body, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, respBody)
+
+	// Saved rows are still reported, import is stopped at the failed one.
+	rows, summary := parseImport(t, respBody)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "created", rows[0].Status)
+	assert.Equal(t, "created", rows[1].Status)
+	assert.Equal(t, importLine{Line: 4, Status: "failed", Error: "connection is lost"}, rows[2])
+	assert.Equal(t, 2, summary.Created)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Contains(t, summary.Error, "connection is lost")
+}
+
+func TestExportUserUrls(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	created := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	for index := 0; index < 1234; index++ {
+		id := fmt.Sprintf("exp%04d", index)
+		repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: id, Original: "https://example.com/" + id, Short: "http://127.0.0.1:8080/" + id,
+			UserID: "owner0000001", CreatedAt: created.Add(time.Duration(index) * time.Minute), Tags: []string{"a", "b c"}})
+	}
+	repository2.GlobalRepository.Insert(context.Background(), repository2.URL{ID: "foreign", Original: "https://example.com/foreign", UserID: "stranger0001"})
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export?format=xml", "owner0000001", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1235)
+	assert.Equal(t, []string{"original_url", "alias", "tags", "expires_at", "short_url", "title", "clicks", "created_at"}, records[0])
+	assert.Equal(t, []string{"https://example.com/exp1233", "exp1233", "a,b c", "", "http://127.0.0.1:8080/exp1233", "", "0", "2023-05-02T08:33:00Z"}, records[1])
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export?format=json&order=asc", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var urls []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &urls))
+	require.Len(t, urls, 1234)
+	assert.Equal(t, "exp0000", urls[0].ID)
+	assert.Equal(t, "exp1233", urls[1233].ID)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export?format=ndjson&tag=missing", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, body)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export?format=ndjson", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	lines := 0
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var url map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &url))
+		assert.NotEqual(t, "foreign", url["id"])
+		lines++
+	}
+	assert.Equal(t, 1234, lines)
+
+	resp, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/export?format=json", "stranger0001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"id":"foreign"`)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/quota"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"time"
+)
+
+// trashSince returns time after which deleted urls still can be restored.
+func trashSince(now time.Time) time.Time {
+	return now.Add(-config.AppConfig.TrashGracePeriod)
+}
+
+// GetUserTrash handler returns user's deleted urls which still can be restored and time when they will be purged.
+func GetUserTrash(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	urls, err := repository.GlobalRepository.GetDeletedByUser(r.Context(), userID, trashSince(time.Now()))
+	if err != nil {
+		http.Error(w, "Can't get urls from repository.", http.StatusInternalServerError)
+		return
+	}
+
+	if len(urls) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	type responseElement struct {
+		ID          string    `json:"id"`
+		ShortURL    string    `json:"short_url"`
+		OriginalURL string    `json:"original_url"`
+		DeletedAt   time.Time `json:"deleted_at"`
+		PurgeAt     time.Time `json:"purge_at"`
+	}
+
+	v := make([]responseElement, len(urls))
+
+	for index, url := range urls {
+		v[index] = responseElement{
+			ID:          url.ID,
+			ShortURL:    url.Short,
+			OriginalURL: url.Original,
+			DeletedAt:   *url.DeletedAt,
+			PurgeAt:     url.DeletedAt.Add(config.AppConfig.TrashGracePeriod),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+// RestoreUserUrls handler restores user's deleted urls by ids in request body, urls deleted before grace period are skipped.
+func RestoreUserUrls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(utils.ContextKey("userID")).(string)
+
+	var urlIDs []string
+
+	if err := json.NewDecoder(r.Body).Decode(&urlIDs); err != nil {
+		http.Error(w, "Can't unmarshal body data.", http.StatusBadRequest)
+		return
+	}
+
+	if len(urlIDs) == 0 {
+		http.Error(w, "Can't handle empty id array in body.", http.StatusBadRequest)
+		return
+	}
+
+	remaining, err := quota.Remaining(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if remaining >= 0 && len(urlIDs) > remaining {
+		http.Error(w, "Links quota exceeded.", http.StatusForbidden)
+		return
+	}
+
+	restored, err := repository.GlobalRepository.RestoreManyByUser(r.Context(), urlIDs, userID, trashSince(time.Now()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if restored == nil {
+		restored = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Restored []string `json:"restored"`
+	}{Restored: restored})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/unfurl"
+	"log"
+	"time"
+)
+
+// fetchPages queues fetching of title, description and favicon of destination pages of urls and saving them to urls.
+// Empty urls and urls which already have page metadata are skipped, urls which don't fit the queue are dropped,
+// nothing is fetched if unfurl.GlobalFetcher is nil.
+func fetchPages(urls ...repository.URL) {
+	fetcher := unfurl.GlobalFetcher
+	if fetcher == nil {
+		return
+	}
+
+	for _, url := range urls {
+		if url.Page != nil || len(url.ID) == 0 {
+			continue
+		}
+
+		id, original := url.ID, url.Original
+		queued := fetcher.Enqueue(original, func(ctx context.Context, metadata unfurl.Metadata, err error) {
+			if err != nil {
+				log.Printf("Can't fetch page of %s: %v", id, err)
+				return
+			}
+
+			page := repository.Page{
+				Title:       metadata.Title,
+				Description: metadata.Description,
+				Favicon:     metadata.Favicon,
+				FetchedAt:   time.Now(),
+			}
+
+			if err := repository.GlobalRepository.SetPage(ctx, id, original, page); err != nil {
+				log.Printf("Can't save page of %s: %v", id, err)
+			}
+		})
+		if !queued {
+			log.Printf("Page fetching queue is full, page of %s is skipped", id)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"github.com/LorezV/url-shorter.git/internal/unfurl"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchPages(t *testing.T) {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page ` + strings.TrimPrefix(r.URL.Path, "/") + `</title>
+<meta name="description" content="About ` + r.URL.Path + `"></head></html>`))
+	}))
+	defer destination.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unfurl.GlobalFetcher = unfurl.MakeFetcher(time.Second, 4096, 2, 100, true)
+	go unfurl.GlobalFetcher.Run(ctx)
+	defer func() {
+		cancel()
+		unfurl.GlobalFetcher = nil
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"`+destination.URL+`/single"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten/batch", "owner0000001",
+		`[{"correlation_id":"1","original_url":"`+destination.URL+`/first"},{"correlation_id":"2","original_url":"`+destination.URL+`/second"}]`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	page := func(original string) *repository2.Page {
+		url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), original)
+		require.True(t, ok)
+		return url.Page
+	}
+
+	for _, path := range []string{"/single", "/first", "/second"} {
+		require.Eventually(t, func() bool {
+			return page(destination.URL+path) != nil
+		}, time.Second, 10*time.Millisecond, path)
+		assert.Equal(t, "Page "+strings.TrimPrefix(path, "/"), page(destination.URL+path).Title)
+		assert.Equal(t, "About "+path, page(destination.URL+path).Description)
+		assert.Equal(t, destination.URL+"/favicon.ico", page(destination.URL+path).Favicon)
+	}
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls?order=asc", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var listing []struct {
+		Page *repository2.Page `json:"page"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &listing))
+	require.Len(t, listing, 3)
+	require.NotNil(t, listing[0].Page)
+	assert.Equal(t, "Page single", listing[0].Page.Title)
+
+	url, _ := repository2.GlobalRepository.GetByOriginal(context.Background(), destination.URL+"/single")
+
+	resp, body = testRequest(t, ts, http.MethodGet, "/"+url.ID+"+", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `<meta property="og:title" content="Page single">`)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"original_url":"`+destination.URL+`/renamed"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Eventually(t, func() bool {
+		url, _ := repository2.GlobalRepository.Get(context.Background(), url.ID)
+		return url.Page != nil && url.Page.Title == "Page renamed"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFetchPagesOnlyForNewDestination(t *testing.T) {
+	var requests int32
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer destination.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unfurl.GlobalFetcher = unfurl.MakeFetcher(time.Second, 4096, 1, 100, true)
+	go unfurl.GlobalFetcher.Run(ctx)
+	defer func() {
+		cancel()
+		unfurl.GlobalFetcher = nil
+	}()
+
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, body := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"`+destination.URL+`/first"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 1 }, time.Second, 10*time.Millisecond)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), destination.URL+"/first")
+	require.True(t, ok)
+	require.Nil(t, url.Page)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"title":"Renamed"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001", `{"original_url":"`+destination.URL+`/second"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Queue is drained in order by one worker, so fetch queued by title edit would come before the second one.
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
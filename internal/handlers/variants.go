@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits of url variants count and weight.
+const (
+	maxVariants      = 10
+	maxVariantWeight = 1000
+)
+
+// variantCookiePrefix is prefix of cookie name which holds variant assigned to client.
+const variantCookiePrefix = "variant_"
+
+// normalizeVariants checks weighted destinations of url and normalizes them, empty list means no split.
+func normalizeVariants(variants []repository.Variant) ([]repository.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return nil, fmt.Errorf("url must have from 2 to %d variants", maxVariants)
+	}
+
+	total := 0
+	normalized := make([]repository.Variant, 0, len(variants))
+
+	for index, variant := range variants {
+		if variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("variant %d: weight must be from 0 to %d", index, maxVariantWeight)
+		}
+
+		destination, err := normalizeDestination(fmt.Sprintf("variant %d destination", index), variant.Destination)
+		if err != nil {
+			return nil, err
+		}
+
+		total += variant.Weight
+		variant.Destination = destination
+		normalized = append(normalized, variant)
+	}
+
+	if total == 0 {
+		return nil, errors.New("at least one variant must have positive weight")
+	}
+
+	return normalized, nil
+}
+
+// assignVariant returns index of url variant for client. Variant from cookie is kept while it has positive weight,
+// new clients are assigned by hash of their ip, so the same client gets the same variant without cookie too.
+func assignVariant(r *http.Request, url repository.URL) int {
+	if cookie, err := r.Cookie(variantCookiePrefix + url.ID); err == nil {
+		variant, err := strconv.Atoi(cookie.Value)
+		if err == nil && variant >= 0 && variant < len(url.Variants) && url.Variants[variant].Weight > 0 {
+			return variant
+		}
+	}
+
+	total := 0
+	for _, variant := range url.Variants {
+		total += variant.Weight
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(url.ID + "|" + clientip.FromRequest(r)))
+	point := int(hash.Sum32() % uint32(total))
+
+	for index, variant := range url.Variants {
+		if point < variant.Weight {
+			return index
+		}
+		point -= variant.Weight
+	}
+
+	return len(url.Variants) - 1
+}
+
+// setVariantCookie remembers variant assigned to client for config.AppConfig.VariantCookieTTL.
+func setVariantCookie(w http.ResponseWriter, url repository.URL, variant int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookiePrefix + url.ID,
+		Value:    strconv.Itoa(variant),
+		Path:     "/" + url.ID,
+		Expires:  time.Now().Add(config.AppConfig.VariantCookieTTL),
+		HttpOnly: true,
+		Secure:   config.AppConfig.EnableHTTPS,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// RecordConversion handler counts conversion of variant assigned to client, landing pages call it after goal is reached.
+func RecordConversion(w http.ResponseWriter, r *http.Request) {
+	url, ok := repository.GlobalRepository.Get(r.Context(), chi.URLParam(r, "id"))
+	if !ok || url.IsDeleted {
+		http.Error(w, "URL with this id not found!", http.StatusNotFound)
+		return
+	}
+
+	if len(url.Variants) == 0 {
+		http.Error(w, "URL has no variants.", http.StatusNotFound)
+		return
+	}
+
+	err := repository.GlobalRepository.RecordVariantEvent(r.Context(), url.ID, assignVariant(r, url), repository.VariantConversion)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type variantStatsResponse struct {
+	Variant        int     `json:"variant"`
+	Destination    string  `json:"destination"`
+	Weight         int     `json:"weight"`
+	Clicks         int64   `json:"clicks"`
+	Conversions    int64   `json:"conversions"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+// GetUserURLStats handler returns clicks of user's url by country and clicks, conversions and conversion rate of its variants.
+func GetUserURLStats(w http.ResponseWriter, r *http.Request) {
+	url, ok := getOwnURL(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := repository.GlobalRepository.GetVariantStats(r.Context(), url.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	countries, err := repository.GlobalRepository.GetCountryStats(r.Context(), url.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	variants := make([]variantStatsResponse, len(url.Variants))
+	for index, variant := range url.Variants {
+		variants[index] = variantStatsResponse{Variant: index, Destination: variant.Destination, Weight: variant.Weight}
+	}
+
+	for _, value := range stats {
+		if value.Variant < 0 || value.Variant >= len(variants) {
+			continue
+		}
+
+		variants[value.Variant].Clicks = value.Clicks
+		variants[value.Variant].Conversions = value.Conversions
+		if value.Clicks > 0 {
+			variants[value.Variant].ConversionRate = float64(value.Conversions) / float64(value.Clicks)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		ID        string                    `json:"id"`
+		Clicks    int64                     `json:"clicks"`
+		Variants  []variantStatsResponse    `json:"variants,omitempty"`
+		Countries []repository.CountryStats `json:"countries,omitempty"`
+	}{ID: url.ID, Clicks: url.Clicks, Variants: variants, Countries: countries})
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	repository2 "github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariants(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	resp, _ := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing","variants":[{"destination":"https://example.com/a","weight":50}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing","variants":[{"destination":"https://example.com/a","weight":0},{"destination":"https://example.com/b","weight":0}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001",
+		`{"url":"https://example.com/landing","variants":[{"destination":"https://example.com/a","weight":50},{"destination":"https://example.com/b","weight":50}]}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), "https://example.com/landing")
+	require.True(t, ok)
+	require.Len(t, url.Variants, 2)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", nil)
+	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	first := resp.Header.Get("Location")
+	assert.Contains(t, []string{"https://example.com/a", "https://example.com/b"}, first)
+	assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+
+	var cookie *http.Cookie
+	for _, value := range resp.Cookies() {
+		if value.Name == "variant_"+url.ID {
+			cookie = value
+		}
+	}
+	require.NotNil(t, cookie)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00002", "", nil)
+	assert.Equal(t, first, resp.Header.Get("Location"), "Client with the same ip gets the same variant.")
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00003", "", map[string]string{"Cookie": "variant_" + url.ID + "=1"})
+	assert.Equal(t, "https://example.com/b", resp.Header.Get("Location"))
+
+	resp, _ = testUserRequest(t, ts, http.MethodPost, "/"+url.ID+"/conversion", "visitor00003", "", map[string]string{"Cookie": "variant_" + url.ID + "=1"})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body := testUserRequest(t, ts, http.MethodGet, "/api/user/urls/"+url.ID+"/stats", "owner0000001", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var stats struct {
+		Clicks   int64 `json:"clicks"`
+		Variants []struct {
+			Variant        int     `json:"variant"`
+			Destination    string  `json:"destination"`
+			Clicks         int64   `json:"clicks"`
+			Conversions    int64   `json:"conversions"`
+			ConversionRate float64 `json:"conversion_rate"`
+		} `json:"variants"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &stats))
+
+	assert.Equal(t, int64(3), stats.Clicks)
+	require.Len(t, stats.Variants, 2)
+	assert.Equal(t, int64(3), stats.Variants[0].Clicks+stats.Variants[1].Clicks)
+	assert.Equal(t, int64(1), stats.Variants[1].Conversions)
+	assert.InDelta(t, 1/float64(stats.Variants[1].Clicks), stats.Variants[1].ConversionRate, 0.001)
+
+	resp, _ = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/"+url.ID+"/stats", "stranger0001", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = testUserRequest(t, ts, http.MethodPatch, "/api/user/urls/"+url.ID, "owner0000001",
+		`{"variants":[{"destination":"https://example.com/a","weight":10},{"destination":"https://example.com/c","weight":90}]}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, body = testUserRequest(t, ts, http.MethodGet, "/api/user/urls/"+url.ID+"/stats", "owner0000001", "", nil)
+	require.NoError(t, json.Unmarshal([]byte(body), &stats))
+	assert.Equal(t, "https://example.com/c", stats.Variants[1].Destination)
+	assert.Zero(t, stats.Variants[0].Clicks+stats.Variants[1].Clicks, "Changed variants reset stats.")
+}
+
+func TestVariantsAssignedAfterGates(t *testing.T) {
+	repository2.GlobalRepository = repository2.MakeMemoryRepository()
+
+	ts := makeLinksServer()
+	defer ts.Close()
+
+	variants := `"variants":[{"destination":"https://example.com/a","weight":50},{"destination":"https://example.com/b","weight":50}]`
+	notBefore := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	for original, options := range map[string]string{
+		"https://example.com/locked": `"password":"secret1234"`,
+		"https://example.com/soon":   `"not_before":"` + notBefore + `"`,
+	} {
+		resp, body := testUserRequest(t, ts, http.MethodPost, "/api/shorten", "owner0000001", `{"url":"`+original+`",`+options+`,`+variants+`}`, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+		url, ok := repository2.GlobalRepository.GetByOriginal(context.Background(), original)
+		require.True(t, ok)
+
+		resp, _ = testUserRequest(t, ts, http.MethodGet, "/"+url.ID, "visitor00001", "", nil)
+		assert.Empty(t, resp.Header.Get("Location"), original)
+		for _, cookie := range resp.Cookies() {
+			assert.NotEqual(t, "variant_"+url.ID, cookie.Name, original)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/unfurl"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxRedirects is limit of redirects followed from destination to final response.
+const MaxRedirects = 10
+
+// UserAgent is sent with every check request, so site owners can recognize the checker.
+const UserAgent = "url-shorter-healthcheck/1.0"
+
+// ErrorTooManyRedirects is error which returning when redirect chain is longer than MaxRedirects.
+var ErrorTooManyRedirects = errors.New("too many redirects")
+
+// Result is outcome of destination check.
+type Result struct {
+	// Status is http status of final response, zero when request failed.
+	Status int
+	// Latency is time from first request to final response headers.
+	Latency time.Duration
+	// Redirects are urls which destination redirected through, in order.
+	Redirects []string
+	Error     string
+	CheckedAt time.Time
+}
+
+// Broken reports whether destination looks dead: request failed, page is missing or server is failing.
+// Access restricted and rate limited responses aren't broken, the page exists.
+func (r Result) Broken() bool {
+	switch {
+	case len(r.Error) > 0:
+		return true
+	case r.Status == http.StatusUnauthorized, r.Status == http.StatusForbidden, r.Status == http.StatusTooManyRequests:
+		return false
+	}
+
+	return r.Status >= 400
+}
+
+// Checker checks destinations with HEAD requests, falling back to GET for servers which don't support HEAD.
+// Requests to the same host are spaced by HostDelay, including hops of redirect chains.
+type Checker struct {
+	Client      *http.Client
+	Concurrency int
+	HostDelay   time.Duration
+
+	mutex *sync.Mutex
+	hosts map[string]time.Time
+}
+
+// MakeChecker is constructor for Checker, timeout limits every request of check. Connections are made only to public
+// addresses, like unfurl.Fetcher does, with allowPrivate they are allowed, it's meant for tests only.
+func MakeChecker(timeout time.Duration, concurrency int, hostDelay time.Duration, allowPrivate bool) *Checker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = unfurl.PublicOnly
+	}
+
+	return &Checker{
+		Client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          concurrency,
+				IdleConnTimeout:       time.Minute,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Concurrency: concurrency,
+		HostDelay:   hostDelay,
+		mutex:       &sync.Mutex{},
+		hosts:       make(map[string]time.Time),
+	}
+}
+
+// CheckAll checks destinations with at most Concurrency requests at a time and reports result of each one
+// by its index. Report is called from many goroutines. CheckAll returns when every destination is checked
+// or context is done.
+func (c *Checker) CheckAll(ctx context.Context, destinations []string, report func(index int, result Result)) {
+	semaphore := make(chan struct{}, c.Concurrency)
+	var wg sync.WaitGroup
+
+	for index, destination := range destinations {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(index int, destination string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			result := c.Check(ctx, destination)
+			if ctx.Err() == nil {
+				report(index, result)
+			}
+		}(index, destination)
+	}
+
+	wg.Wait()
+}
+
+// Check follows redirect chain of destination and returns status of final response.
+func (c *Checker) Check(ctx context.Context, destination string) Result {
+	started := time.Now()
+	result := Result{}
+
+	current, err := url.Parse(destination)
+	for err == nil {
+		var status int
+		var location string
+
+		status, location, err = c.hop(ctx, current)
+		if err != nil {
+			break
+		}
+
+		if status < 300 || status >= 400 || len(location) == 0 {
+			result.Status = status
+			break
+		}
+
+		if len(result.Redirects) == MaxRedirects {
+			err = ErrorTooManyRedirects
+			break
+		}
+
+		current, err = current.Parse(location)
+		if err == nil {
+			result.Redirects = append(result.Redirects, current.String())
+		}
+	}
+
+	if errors.Is(err, unfurl.ErrorForbiddenAddress) {
+		// Dial errors of private addresses aren't reported, they would reveal internal network.
+		result.Error = unfurl.ErrorForbiddenAddress.Error()
+	} else if err != nil {
+		result.Error = err.Error()
+	}
+	result.Latency = time.Since(started)
+	result.CheckedAt = time.Now()
+
+	return result
+}
+
+// hop requests target once with HEAD and, if server rejects HEAD, with GET. Returns status and Location header.
+func (c *Checker) hop(ctx context.Context, target *url.URL) (int, string, error) {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return 0, "", fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	status, location, err := c.request(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, location, err = c.request(ctx, http.MethodGet, target)
+	}
+
+	return status, location, err
+}
+
+func (c *Checker) request(ctx context.Context, method string, target *url.URL) (int, string, error) {
+	if err := c.wait(ctx, strings.ToLower(target.Host)); err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+// wait reserves next request slot of host and sleeps until it comes.
+func (c *Checker) wait(ctx context.Context, host string) error {
+	c.mutex.Lock()
+	now := time.Now()
+	at := c.hosts[host]
+	if at.Before(now) {
+		at = now
+	}
+	c.hosts[host] = at.Add(c.HostDelay)
+
+	for key, value := range c.hosts {
+		if value.Before(now) {
+			delete(c.hosts, key)
+		}
+	}
+	c.mutex.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package healthcheck
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/unfurl"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+		case "/moved-again":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/get-only":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/private":
+			w.WriteHeader(http.StatusForbidden)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	checker := MakeChecker(100*time.Millisecond, 2, 0, true)
+
+	tests := []struct {
+		name      string
+		path      string
+		status    int
+		redirects []string
+		broken    bool
+	}{
+		{name: "Page is available.", path: "/ok", status: http.StatusOK},
+		{name: "Redirect chain is followed.", path: "/moved", status: http.StatusOK, redirects: []string{ts.URL + "/moved-again", ts.URL + "/ok"}},
+		{name: "Server without HEAD support.", path: "/get-only", status: http.StatusOK},
+		{name: "Access restricted page isn't broken.", path: "/private", status: http.StatusForbidden},
+		{name: "Missing page.", path: "/missing", status: http.StatusNotFound, broken: true},
+		{name: "Timeout.", path: "/slow", broken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checker.Check(context.Background(), ts.URL+tt.path)
+			assert.Equal(t, tt.status, result.Status)
+			assert.Equal(t, tt.redirects, result.Redirects)
+			assert.Equal(t, tt.broken, result.Broken(), result.Error)
+			assert.False(t, result.CheckedAt.IsZero())
+		})
+	}
+
+	result := checker.Check(context.Background(), ts.URL+"/loop")
+	assert.Equal(t, ErrorTooManyRedirects.Error(), result.Error)
+	assert.Len(t, result.Redirects, MaxRedirects)
+
+	result = checker.Check(context.Background(), "ftp://example.com/file")
+	assert.True(t, result.Broken())
+}
+
+func TestCheckAll(t *testing.T) {
+	var (
+		active, peak int32
+		mutex        sync.Mutex
+		times        []time.Time
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		mutex.Lock()
+		if current > peak {
+			peak = current
+		}
+		times = append(times, time.Now())
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	destinations := []string{ts.URL + "/1", ts.URL + "/2", ts.URL + "/3", ts.URL + "/4"}
+	results := make([]Result, len(destinations))
+
+	checker := MakeChecker(time.Second, 3, 50*time.Millisecond, true)
+	checker.CheckAll(context.Background(), destinations, func(index int, result Result) {
+		results[index] = result
+	})
+
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.Status)
+	}
+
+	require.Len(t, times, len(destinations))
+	for index := 1; index < len(times); index++ {
+		assert.GreaterOrEqual(t, times[index].Sub(times[index-1]), 45*time.Millisecond, "requests to the same host are spaced")
+	}
+	assert.Equal(t, int32(1), peak)
+}
+
+func TestCheckAllConcurrency(t *testing.T) {
+	var active, peak int32
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			value := atomic.LoadInt32(&peak)
+			if current <= value || atomic.CompareAndSwapInt32(&peak, value, current) {
+				break
+			}
+		}
+
+		time.Sleep(30 * time.Millisecond)
+	})
+
+	destinations := make([]string, 6)
+	for index := range destinations {
+		ts := httptest.NewServer(handler)
+		defer ts.Close()
+		destinations[index] = ts.URL
+	}
+
+	var checked int32
+	MakeChecker(time.Second, 2, time.Second, true).CheckAll(context.Background(), destinations, func(index int, result Result) {
+		atomic.AddInt32(&checked, 1)
+	})
+
+	assert.Equal(t, int32(len(destinations)), checked)
+	assert.Equal(t, int32(2), peak)
+}
+
+func TestCheckForbiddenAddress(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	checker := MakeChecker(time.Second, 1, 0, false)
+
+	// Open and closed ports must look the same, otherwise checker would scan internal network.
+	for _, destination := range []string{ts.URL, "http://localhost:1/", "http://10.0.0.1/"} {
+		result := checker.Check(context.Background(), destination)
+		assert.Equal(t, unfurl.ErrorForbiddenAddress.Error(), result.Error, destination)
+		assert.True(t, result.Broken(), destination)
+	}
+
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/healthcheck"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"log"
+	"time"
+)
+
+// RunHealthCheck checks destinations of urls which weren't checked for interval, at most batch urls every interval,
+// until context is done.
+func RunHealthCheck(ctx context.Context, interval time.Duration, batch int, checker *healthcheck.Checker) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		CheckHealth(ctx, time.Now().Add(-interval), batch, checker)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckHealth checks destinations of urls which weren't checked since given time and saves results.
+func CheckHealth(ctx context.Context, checkedBefore time.Time, batch int, checker *healthcheck.Checker) {
+	urls, err := repository.GlobalRepository.GetDueHealthChecks(ctx, checkedBefore, batch)
+	if err != nil {
+		log.Printf("Can't get urls to check: %v", err)
+		return
+	}
+
+	destinations := make([]string, len(urls))
+	for index, url := range urls {
+		destinations[index] = url.Original
+	}
+
+	checker.CheckAll(ctx, destinations, func(index int, result healthcheck.Result) {
+		health := repository.Health{
+			Status:    result.Status,
+			LatencyMs: result.Latency.Milliseconds(),
+			Redirects: result.Redirects,
+			Error:     result.Error,
+			Broken:    result.Broken(),
+			CheckedAt: result.CheckedAt,
+		}
+
+		if err := repository.GlobalRepository.SetHealth(ctx, urls[index].ID, urls[index].Original, health); err != nil {
+			log.Printf("Can't save health of %s: %v", urls[index].ID, err)
+		}
+	})
+}
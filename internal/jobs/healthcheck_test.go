@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/healthcheck"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/alive" {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	repository.GlobalRepository = repository.MakeMemoryRepository()
+	repository.GlobalRepository.Insert(ctx, repository.URL{ID: "alive", Original: ts.URL + "/alive", UserID: "user"})
+	repository.GlobalRepository.Insert(ctx, repository.URL{ID: "dead", Original: ts.URL + "/dead", UserID: "user"})
+	repository.GlobalRepository.Insert(ctx, repository.URL{ID: "deleted", Original: ts.URL + "/deleted", UserID: "user"})
+	repository.GlobalRepository.DeleteManyByUser(ctx, []string{"deleted"}, "user")
+
+	checker := healthcheck.MakeChecker(time.Second, 2, 0, true)
+	CheckHealth(ctx, time.Now(), 10, checker)
+
+	alive, _ := repository.GlobalRepository.Get(ctx, "alive")
+	require.NotNil(t, alive.Health)
+	assert.Equal(t, http.StatusOK, alive.Health.Status)
+	assert.False(t, alive.Health.Broken)
+
+	dead, _ := repository.GlobalRepository.Get(ctx, "dead")
+	require.NotNil(t, dead.Health)
+	assert.Equal(t, http.StatusNotFound, dead.Health.Status)
+	assert.True(t, dead.Health.Broken)
+
+	deleted, _ := repository.GlobalRepository.Get(ctx, "deleted")
+	assert.Nil(t, deleted.Health)
+
+	due, err := repository.GlobalRepository.GetDueHealthChecks(ctx, time.Now().Add(-time.Minute), 0)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	dead.Original = ts.URL + "/alive-now"
+	dead, err = repository.GlobalRepository.Update(ctx, dead, 0)
+	require.NoError(t, err)
+	assert.Nil(t, dead.Health, "changed destination is checked again")
+}
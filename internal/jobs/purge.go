@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"log"
+	"time"
+)
+
+// RunPurge hard-deletes urls which were deleted longer than grace period ago, every interval until context is done.
+func RunPurge(ctx context.Context, interval, grace time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := repository.GlobalRepository.PurgeDeleted(ctx, time.Now().Add(-grace))
+		if err != nil {
+			log.Printf("Can't purge deleted urls: %v", err)
+		} else if count > 0 {
+			log.Printf("Purged %d deleted urls.", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
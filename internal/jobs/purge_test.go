@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPurge(t *testing.T) {
+	repository.GlobalRepository = repository.MakeMemoryRepository()
+	repository.GlobalRepository.Insert(context.Background(), repository.URL{ID: "purge1", Original: "https://example.com/1", UserID: "user"})
+	repository.GlobalRepository.Insert(context.Background(), repository.URL{ID: "purge2", Original: "https://example.com/2", UserID: "user"})
+	repository.GlobalRepository.DeleteManyByUser(context.Background(), []string{"purge1"}, "user")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	RunPurge(ctx, time.Hour, -time.Minute)
+
+	_, ok := repository.GlobalRepository.Get(context.Background(), "purge1")
+	assert.False(t, ok)
+
+	_, ok = repository.GlobalRepository.Get(context.Background(), "purge2")
+	assert.True(t, ok)
+}
@@ -4,10 +4,18 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/hmac"
+	"crypto/subtle"
 	"encoding/hex"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/ratelimit"
 	"github.com/LorezV/url-shorter.git/internal/utils"
 	"io"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -81,3 +89,109 @@ func Authorization(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminAuthorization middleware checks admin bearer token from Authorization header and adds admin actor name to context.
+func AdminAuthorization(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(config.AppConfig.AdminToken) == 0 {
+			http.Error(w, "Admin API is disabled.", http.StatusForbidden)
+			return
+		}
+
+		if !validBearer(r.Header.Get("Authorization"), config.AppConfig.AdminToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Invalid admin token.", http.StatusUnauthorized)
+			return
+		}
+
+		actor := "admin"
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			actor += "@" + host
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), utils.ContextKey("admin"), actor))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validBearer reports whether authorization header value has Bearer scheme with expected token.
+func validBearer(authorization, expected string) bool {
+	const scheme = "Bearer "
+	if len(authorization) <= len(scheme) || !strings.EqualFold(authorization[:len(scheme)], scheme) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(authorization[len(scheme):]), []byte(expected)) == 1
+}
+
+// RateLimit middleware takes token from user, api key and client ip buckets of scope and rejects request with 429 status if any of them is empty.
+// Limit is a count of requests per config.AppConfig.RateLimitPeriod, zero limit disables middleware. Client ip is taken
+// by clientip.FromRequest, so clients behind trusted proxies don't share the proxy's bucket.
+func RateLimit(scope string, limit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 || ratelimit.GlobalStore == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var keys []string
+
+			if userID, ok := r.Context().Value(utils.ContextKey("userID")).(string); ok {
+				keys = append(keys, scope+":user:"+userID)
+			}
+
+			if apiKey := r.Header.Get("X-API-Key"); len(apiKey) > 0 {
+				keys = append(keys, scope+":key:"+apiKey)
+			}
+
+			if ip := clientip.FromRequest(r); len(ip) > 0 {
+				keys = append(keys, scope+":ip:"+ip)
+			}
+
+			var strictest *ratelimit.Result
+
+			for _, key := range keys {
+				result, err := ratelimit.GlobalStore.Take(r.Context(), key, limit, config.AppConfig.RateLimitPeriod)
+				if err != nil {
+					log.Printf("Can't take rate limit token for %s: %v", key, err)
+					continue
+				}
+
+				if strictest == nil || isStricter(result, *strictest) {
+					strictest = &result
+				}
+			}
+
+			if strictest == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(strictest.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(strictest.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(strictest.Reset.Seconds()))))
+
+			if !strictest.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(strictest.RetryAfter.Seconds()))))
+				http.Error(w, "Too many requests.", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isStricter reports whether rate limit result a should be reported to client instead of b.
+func isStricter(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+
+	return a.Remaining < b.Remaining
+}
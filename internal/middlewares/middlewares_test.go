@@ -0,0 +1,128 @@
+package middlewares_test
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/clientip"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/middlewares"
+	"github.com/LorezV/url-shorter.git/internal/ratelimit"
+	"github.com/LorezV/url-shorter.git/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRateLimit(t *testing.T) {
+	ratelimit.GlobalStore = ratelimit.MakeMemoryStore()
+	config.AppConfig.RateLimitPeriod = time.Minute
+	require.NoError(t, clientip.SetTrustedProxies(nil))
+
+	t.Cleanup(func() {
+		ratelimit.GlobalStore = nil
+		clientip.SetTrustedProxies(nil)
+	})
+}
+
+func limitedRequest(handler http.Handler, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func makeLimitedHandler(scope string, limit int) http.Handler {
+	return middlewares.RateLimit(scope, limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestRateLimit(t *testing.T) {
+	setupRateLimit(t)
+	handler := makeLimitedHandler("create", 2)
+
+	w := limitedRequest(handler, "192.0.2.1:1000", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+	w = limitedRequest(handler, "192.0.2.1:1001", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = limitedRequest(handler, "192.0.2.1:1002", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = limitedRequest(handler, "192.0.2.2:1000", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRateLimitScopes(t *testing.T) {
+	setupRateLimit(t)
+	create := makeLimitedHandler("create", 1)
+	redirect := makeLimitedHandler("redirect", 1)
+
+	assert.Equal(t, http.StatusNoContent, limitedRequest(create, "192.0.2.1:1000", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(create, "192.0.2.1:1000", nil).Code)
+	assert.Equal(t, http.StatusNoContent, limitedRequest(redirect, "192.0.2.1:1000", nil).Code)
+}
+
+func TestRateLimitUserAndKey(t *testing.T) {
+	setupRateLimit(t)
+	handler := makeLimitedHandler("batch", 1)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1000"
+	r = r.WithContext(context.WithValue(r.Context(), utils.ContextKey("userID"), "user00000001"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Same user from another address is limited by user bucket.
+	r.RemoteAddr = "192.0.2.2:1000"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	assert.Equal(t, http.StatusNoContent, limitedRequest(handler, "192.0.2.3:1000", map[string]string{"X-API-Key": "key1"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(handler, "192.0.2.4:1000", map[string]string{"X-API-Key": "key1"}).Code)
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	setupRateLimit(t)
+	handler := makeLimitedHandler("redirect", 1)
+
+	// Without trusted proxies forwarded header is ignored and the peer is limited.
+	assert.Equal(t, http.StatusNoContent, limitedRequest(handler, "10.0.0.1:1000", map[string]string{"X-Forwarded-For": "198.51.100.1"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(handler, "10.0.0.1:1000", map[string]string{"X-Forwarded-For": "198.51.100.2"}).Code)
+
+	// Clients behind trusted proxy get own buckets.
+	require.NoError(t, clientip.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	assert.Equal(t, http.StatusNoContent, limitedRequest(handler, "10.0.0.1:1000", map[string]string{"X-Forwarded-For": "198.51.100.3"}).Code)
+	assert.Equal(t, http.StatusNoContent, limitedRequest(handler, "10.0.0.1:1000", map[string]string{"X-Forwarded-For": "198.51.100.4"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, limitedRequest(handler, "10.0.0.1:1000", map[string]string{"X-Forwarded-For": "198.51.100.3"}).Code)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	setupRateLimit(t)
+	handler := makeLimitedHandler("create", 0)
+
+	for i := 0; i < 3; i++ {
+		w := limitedRequest(handler, "192.0.2.1:1000", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
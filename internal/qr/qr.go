@@ -0,0 +1,194 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Limits and defaults of rendering options.
+const (
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultSize   = 256
+	MaxMargin     = 16
+	DefaultMargin = 4
+	DefaultLevel  = "M"
+)
+
+// levels maps error correction level letters to recovery levels, from 7% to 30% of restorable data.
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// ErrorTooSmall is returned when image is too small to draw every module of the code with at least one pixel.
+var ErrorTooSmall = errors.New("size is too small for this code")
+
+// Options describe how code is rendered.
+type Options struct {
+	// Size is width and height of image in pixels.
+	Size int
+	// Level is error correction level: L, M, Q or H.
+	Level string
+	// Margin is width of quiet zone around the code in modules.
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions returns options of black on white code with standard quiet zone.
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		Level:      DefaultLevel,
+		Margin:     DefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Validate checks options limits.
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("size must be from %d to %d", MinSize, MaxSize)
+	}
+
+	if _, ok := levels[o.Level]; !ok {
+		return errors.New("level must be one of L, M, Q, H")
+	}
+
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("margin must be from 0 to %d", MaxMargin)
+	}
+
+	return nil
+}
+
+// ParseColor parses hex color in RGB, RGBA, RRGGBB or RRGGBBAA form with optional leading #.
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 || len(hex) == 4 {
+		short := hex
+		hex = ""
+		for _, digit := range short {
+			hex += string([]rune{digit, digit})
+		}
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	parsed, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", value)
+	}
+
+	return color.RGBA{R: uint8(parsed >> 24), G: uint8(parsed >> 16), B: uint8(parsed >> 8), A: uint8(parsed)}, nil
+}
+
+// modules returns dark modules of code for content without quiet zone, modules[y][x] is true if module is dark.
+func modules(content string, options Options) ([][]bool, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(content, levels[options.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+
+	return code.Bitmap(), nil
+}
+
+// PNG renders content as PNG image. Modules are scaled by whole pixels and the code is centered,
+// so the rest of the image is filled with background.
+func PNG(content string, options Options) ([]byte, error) {
+	bitmap, err := modules(content, options)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(bitmap) + 2*options.Margin
+	scale := options.Size / total
+	if scale < 1 {
+		return nil, ErrorTooSmall
+	}
+	offset := (options.Size-total*scale)/2 + options.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, options.Size, options.Size), color.Palette{options.Background, options.Foreground})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG renders content as SVG image. The code is drawn as single path in module units, so it scales without blur.
+func SVG(content string, options Options) ([]byte, error) {
+	bitmap, err := modules(content, options)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(bitmap) + 2*options.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		options.Size, options.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d"%s/>`, total, total, fill(options.Background))
+	buf.WriteString(`<path d="`)
+
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+options.Margin, y+options.Margin, x-start, x-start)
+		}
+	}
+
+	fmt.Fprintf(&buf, `"%s/></svg>`, fill(options.Foreground))
+
+	return buf.Bytes(), nil
+}
+
+// fill returns SVG fill attributes of color.
+func fill(c color.RGBA) string {
+	attributes := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		attributes += fmt.Sprintf(` fill-opacity="%.3g"`, float64(c.A)/0xff)
+	}
+
+	return attributes
+}
@@ -0,0 +1,98 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  color.RGBA
+		fails bool
+	}{
+		{value: "#000", want: color.RGBA{A: 0xff}},
+		{value: "1a2B3c", want: color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{value: "fff8", want: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x88}},
+		{value: "#ffffff80", want: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}},
+		{value: "", fails: true},
+		{value: "red", fails: true},
+		{value: "#12345", fails: true},
+		{value: "+12345", fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseColor(tt.value)
+			if tt.fails {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPNG(t *testing.T) {
+	options := DefaultOptions()
+	options.Size = 300
+	options.Foreground = color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+
+	data, err := PNG("http://127.0.0.1:8080/abc", options)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	bitmap, err := modules("http://127.0.0.1:8080/abc", options)
+	require.NoError(t, err)
+
+	// Version 2 code has 25 modules, with quiet zone 33 modules are scaled by 9 pixels and centered.
+	require.Len(t, bitmap, 25)
+	offset := (300-33*9)/2 + 4*9
+	for _, point := range [][2]int{{0, 0}, {7, 7}, {8, 8}, {24, 0}} {
+		want := options.Background
+		if bitmap[point[1]][point[0]] {
+			want = options.Foreground
+		}
+
+		r, g, b, _ := img.At(offset+point[0]*9+4, offset+point[1]*9+4).RGBA()
+		assert.Equal(t, [3]uint8{want.R, want.G, want.B}, [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)})
+	}
+	r, g, b, _ := img.At(offset-1, offset-1).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b})
+
+	options.Size = MinSize
+	options.Margin = MaxMargin
+	_, err = PNG(strings.Repeat("a", 200), options)
+	assert.ErrorIs(t, err, ErrorTooSmall)
+
+	options.Level = "X"
+	_, err = PNG("http://127.0.0.1:8080/abc", options)
+	assert.Error(t, err)
+}
+
+func TestSVG(t *testing.T) {
+	options := DefaultOptions()
+	options.Margin = 2
+	options.Background = color.RGBA{R: 0xff, G: 0xff, B: 0xff}
+
+	data, err := SVG("http://127.0.0.1:8080/abc", options)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 29 29"`))
+	assert.Contains(t, svg, `<rect width="29" height="29" fill="#ffffff" fill-opacity="0"/>`)
+	// Top left finder pattern starts with run of 7 dark modules.
+	assert.Contains(t, svg, `<path d="M2 2h7v1h-7z`)
+	assert.True(t, strings.HasSuffix(svg, `" fill="#000000"/></svg>`))
+}
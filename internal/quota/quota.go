@@ -0,0 +1,131 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"os"
+)
+
+// Teams contains teams loaded from config.AppConfig.QuotaTeamsFile.
+var Teams []Team
+
+// Team is a group of users with shared limit of active links.
+type Team struct {
+	Name     string   `json:"name"`
+	MaxLinks int      `json:"max_links"`
+	Members  []string `json:"members"`
+}
+
+// Usage describes count of active links against limit for user or team. Zero Limit means unlimited.
+type Usage struct {
+	Name  string
+	Used  int
+	Limit int
+}
+
+// Remaining returns count of links which still can be created, negative value means unlimited.
+func (u Usage) Remaining() int {
+	if u.Limit <= 0 {
+		return -1
+	}
+
+	if u.Used >= u.Limit {
+		return 0
+	}
+
+	return u.Limit - u.Used
+}
+
+// ErrorQuotaExceeded is error which returning when user or his team can't own more links.
+var ErrorQuotaExceeded = repository.ErrorQuotaExceeded
+
+// LoadTeams loads teams from json file, empty path means no teams.
+func LoadTeams(path string) error {
+	Teams = nil
+
+	if len(path) == 0 {
+		return nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, &Teams)
+}
+
+// GetUsage returns usage of user personal quota and quotas of all teams user belongs to.
+func GetUsage(ctx context.Context, userID string) (Usage, []Usage, error) {
+	used, err := repository.GlobalRepository.CountActiveByUsers(ctx, []string{userID})
+	if err != nil {
+		return Usage{}, nil, err
+	}
+
+	user := Usage{Name: userID, Used: used, Limit: config.AppConfig.QuotaMaxLinks}
+
+	var teams []Usage
+
+	for _, team := range Teams {
+		if !team.hasMember(userID) {
+			continue
+		}
+
+		used, err = repository.GlobalRepository.CountActiveByUsers(ctx, team.Members)
+		if err != nil {
+			return user, nil, err
+		}
+
+		teams = append(teams, Usage{Name: team.Name, Used: used, Limit: team.MaxLinks})
+	}
+
+	return user, teams, nil
+}
+
+// Limits returns repository limits of user personal quota and quotas of all teams user belongs to, they are passed
+// to repository inserts to be checked atomically.
+func Limits(userID string) []repository.Limit {
+	var limits []repository.Limit
+
+	if config.AppConfig.QuotaMaxLinks > 0 {
+		limits = append(limits, repository.Limit{Users: []string{userID}, Max: config.AppConfig.QuotaMaxLinks})
+	}
+
+	for _, team := range Teams {
+		if team.MaxLinks > 0 && team.hasMember(userID) {
+			limits = append(limits, repository.Limit{Users: team.Members, Max: team.MaxLinks})
+		}
+	}
+
+	return limits
+}
+
+// Remaining returns count of links user can create, negative value means unlimited.
+func Remaining(ctx context.Context, userID string) (int, error) {
+	user, teams, err := GetUsage(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := user.Remaining()
+
+	for _, team := range teams {
+		if r := team.Remaining(); r >= 0 && (remaining < 0 || r < remaining) {
+			remaining = r
+		}
+	}
+
+	return remaining, nil
+}
+
+func (t Team) hasMember(userID string) bool {
+	for _, member := range t.Members {
+		if member == userID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package quota
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemaining(t *testing.T) {
+	repository.GlobalRepository = repository.MakeMemoryRepository()
+	repository.GlobalRepository.Insert(context.Background(), repository.URL{ID: "url1", Original: "https://a.example", UserID: "alice"})
+	repository.GlobalRepository.Insert(context.Background(), repository.URL{ID: "url2", Original: "https://b.example", UserID: "bob"})
+	repository.GlobalRepository.Insert(context.Background(), repository.URL{ID: "url3", Original: "https://c.example", UserID: "bob"})
+	repository.GlobalRepository.DeleteManyByUser(context.Background(), []string{"url3"}, "bob")
+
+	tests := []struct {
+		name      string
+		maxLinks  int
+		teams     []Team
+		userID    string
+		remaining int
+	}{
+		{
+			name:      "Unlimited without user and team limits.",
+			userID:    "alice",
+			remaining: -1,
+		},
+		{
+			name:      "User limit counts only active links.",
+			maxLinks:  2,
+			userID:    "bob",
+			remaining: 1,
+		},
+		{
+			name:      "Team limit is shared between members.",
+			maxLinks:  10,
+			teams:     []Team{{Name: "marketing", MaxLinks: 2, Members: []string{"alice", "bob"}}},
+			userID:    "alice",
+			remaining: 0,
+		},
+		{
+			name:      "Team limit doesn't apply to other users.",
+			teams:     []Team{{Name: "marketing", MaxLinks: 1, Members: []string{"bob"}}},
+			userID:    "alice",
+			remaining: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig.QuotaMaxLinks = tt.maxLinks
+			Teams = tt.teams
+			defer func() {
+				config.AppConfig.QuotaMaxLinks = 0
+				Teams = nil
+			}()
+
+			remaining, err := Remaining(context.Background(), tt.userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.remaining, remaining)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneEvery is a count of Take calls between removing of full buckets from memory.
+const pruneEvery = 1000
+
+// MemoryStore is Store implementation which keeps buckets in process memory.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]bucket
+	calls   int
+}
+
+// MakeMemoryStore is constructor for MemoryStore.
+func MakeMemoryStore() Store {
+	return &MemoryStore{buckets: make(map[string]bucket)}
+}
+
+// Take takes one token from bucket by key.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit int, period time.Duration) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	s.calls++
+	if s.calls%pruneEvery == 0 {
+		s.prune(now, period)
+	}
+
+	b, ok := s.buckets[key]
+	b, result := take(b, ok, limit, period, now)
+	s.buckets[key] = b
+
+	return result, nil
+}
+
+// prune removes buckets which have been refilled completely, they are equal to missing ones.
+func (s *MemoryStore) prune(now time.Time, period time.Duration) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Close does nothing for memory store.
+func (s *MemoryStore) Close() error {
+	return nil
+}
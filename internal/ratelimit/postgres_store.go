@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// PostgresStore is Store implementation which keeps buckets in postgresql table, so limits are shared between instances.
+type PostgresStore struct {
+	database *sql.DB
+	calls    *int64
+}
+
+// MakePostgresStore is constructor for PostgresStore.
+func MakePostgresStore(database *sql.DB) (Store, error) {
+	_, err := database.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS "rate_limit" (
+	"key" VARCHAR(256) NOT NULL,
+	"tokens" DOUBLE PRECISION NOT NULL,
+	"updated_at" TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("key")
+);`)
+	if err != nil {
+		return nil, err
+	}
+
+	return PostgresStore{database: database, calls: new(int64)}, nil
+}
+
+// Take takes one token from bucket by key, bucket row is locked until transaction end.
+func (s PostgresStore) Take(ctx context.Context, key string, limit int, period time.Duration) (Result, error) {
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	if atomic.AddInt64(s.calls, 1)%pruneEvery == 0 {
+		_, err = tx.ExecContext(ctx, `DELETE FROM rate_limit WHERE updated_at < $1`, now.Add(-period))
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO rate_limit (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`, key, limit, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var b bucket
+
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit WHERE key=$1 FOR UPDATE`, key).Scan(&b.tokens, &b.updated)
+	if err != nil {
+		return Result{}, err
+	}
+
+	b, result := take(b, true, limit, period, now)
+
+	_, err = tx.ExecContext(ctx, `UPDATE rate_limit SET tokens=$2, updated_at=$3 WHERE key=$1`, key, b.tokens, b.updated)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+// Close does nothing, database connection is owned by repository.
+func (s PostgresStore) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDatabase connects to database from DATABASE_DSN, test is skipped when it is not set.
+func openTestDatabase(t *testing.T) *sql.DB {
+	dsn := os.Getenv("DATABASE_DSN")
+	if len(dsn) == 0 {
+		t.Skip("DATABASE_DSN is not set")
+	}
+
+	database, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	return database
+}
+
+func TestPostgresStore(t *testing.T) {
+	database := openTestDatabase(t)
+
+	store, err := MakePostgresStore(database)
+	require.NoError(t, err)
+
+	key := fmt.Sprintf("test:ip:%d", time.Now().UnixNano())
+	t.Cleanup(func() { database.Exec(`DELETE FROM rate_limit WHERE key LIKE $1`, key+"%") })
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(context.Background(), key, 3, time.Hour)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := store.Take(context.Background(), key, 3, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	result, err = store.Take(context.Background(), key+":other", 3, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestPostgresStoreConcurrentTakes(t *testing.T) {
+	database := openTestDatabase(t)
+
+	store, err := MakePostgresStore(database)
+	require.NoError(t, err)
+
+	key := fmt.Sprintf("test:concurrent:%d", time.Now().UnixNano())
+	t.Cleanup(func() { database.Exec(`DELETE FROM rate_limit WHERE key=$1`, key) })
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		allowed int
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := store.Take(context.Background(), key, 5, time.Hour)
+			if assert.NoError(t, err) && result.Allowed {
+				mutex.Lock()
+				allowed++
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 5, allowed)
+}
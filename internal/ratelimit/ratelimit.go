@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// GlobalStore it's rate limit buckets storage variable.
+var GlobalStore Store
+
+// Store is a interface for token buckets storage. Bucket with key holds limit tokens and refills them evenly during period.
+type Store interface {
+	Take(ctx context.Context, key string, limit int, period time.Duration) (Result, error)
+	Close() error
+}
+
+// Result describes outcome of taking one token from bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills bucket by time passed since last update and tries to take one token from it.
+func take(b bucket, exists bool, limit int, period time.Duration, now time.Time) (bucket, Result) {
+	rate := float64(limit) / period.Seconds()
+
+	if !exists {
+		b.tokens = float64(limit)
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit), b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	result := Result{Limit: limit}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit) - b.tokens) / rate)
+
+	return b, result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Now()
+
+	b, result := take(bucket{}, false, 2, time.Minute, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	b, result = take(b, true, 2, time.Minute, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	b, result = take(b, true, 2, time.Minute, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	_, result = take(b, true, 2, time.Minute, now.Add(30*time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := MakeMemoryStore()
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(context.Background(), "create:ip:127.0.0.1", 3, time.Hour)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(context.Background(), "create:ip:127.0.0.1", 3, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	result, err = store.Take(context.Background(), "create:ip:127.0.0.2", 3, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
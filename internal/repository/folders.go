@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// Folder entity represent database table folder, folders of user form a tree by ParentID, zero ParentID is root.
+type Folder struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"-"`
+	Name      string    `json:"name"`
+	ParentID  int64     `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagUsage contains tag name and count of user's not deleted urls marked with it.
+type TagUsage struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ErrorFolderNotFound is error which returning when user's folder with id doesn't exist in database.
+var ErrorFolderNotFound = errors.New("folder not found")
+
+// hasTag reports whether tags contains tag.
+func hasTag(tags []string, tag string) bool {
+	for _, value := range tags {
+		if value == tag {
+			return true
+		}
+	}
+
+	return false
+}
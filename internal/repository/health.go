@@ -0,0 +1,18 @@
+package repository
+
+import "time"
+
+// Health is result of last check of url destination by background health checker.
+type Health struct {
+	Status    int       `json:"status,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	Redirects []string  `json:"redirects,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Broken    bool      `json:"broken"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// isBroken reports whether last check of url found its destination broken.
+func isBroken(url URL) bool {
+	return url.Health != nil && url.Health.Broken
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrorQuotaExceeded is error which returning when url can't be inserted because it doesn't fit one of limits.
+var ErrorQuotaExceeded = errors.New("links quota exceeded")
+
+// Limit is a max count of not deleted urls owned by users together. Limits passed to Insert and InsertMany are
+// checked atomically with insert, so concurrent inserts can't exceed them.
+type Limit struct {
+	Users []string
+	Max   int
+}
+
+// remainingOf returns count of urls which still fit all limits by count of users' urls, negative value means unlimited.
+func remainingOf(limits []Limit, count func(userIDs []string) (int, error)) (int, error) {
+	remaining := -1
+
+	for _, limit := range limits {
+		used, err := count(limit.Users)
+		if err != nil {
+			return 0, err
+		}
+
+		left := limit.Max - used
+		if left < 0 {
+			left = 0
+		}
+
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+
+	return remaining, nil
+}
+
+// fitLimits reports which of urls can be inserted when remaining urls fit limits. Urls with already stored original
+// and repeated originals don't take place in limits, so duplicates are found before quota is exceeded.
+func fitLimits(urls []URL, stored func(original string) bool, remaining int) []bool {
+	accepted := make([]bool, len(urls))
+	seen := make(map[string]bool, len(urls))
+
+	for index, url := range urls {
+		if seen[url.Original] || stored(url.Original) {
+			accepted[index] = true
+			continue
+		}
+
+		if remaining == 0 {
+			continue
+		}
+
+		seen[url.Original] = true
+		accepted[index] = true
+		if remaining > 0 {
+			remaining--
+		}
+	}
+
+	return accepted
+}
+
+// limitUsers returns sorted unique users of limits.
+func limitUsers(limits []Limit) []string {
+	unique := make(map[string]bool)
+	for _, limit := range limits {
+		for _, user := range limit.Users {
+			unique[user] = true
+		}
+	}
+
+	users := make([]string, 0, len(unique))
+	for user := range unique {
+		users = append(users, user)
+	}
+
+	sort.Strings(users)
+
+	return users
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitLimits(t *testing.T) {
+	urls := []URL{{Original: "https://a.example"}, {Original: "https://stored.example"}, {Original: "https://b.example"}, {Original: "https://a.example"}, {Original: "https://c.example"}}
+	stored := func(original string) bool { return original == "https://stored.example" }
+
+	assert.Equal(t, []bool{true, true, true, true, true}, fitLimits(urls, stored, -1))
+	assert.Equal(t, []bool{true, true, true, true, false}, fitLimits(urls, stored, 2))
+	assert.Equal(t, []bool{false, true, false, false, false}, fitLimits(urls, stored, 0))
+}
+
+func TestMemoryInsertLimits(t *testing.T) {
+	repository := MakeMemoryRepository()
+	limits := []Limit{{Users: []string{"alice"}, Max: 10}, {Users: []string{"alice", "bob"}, Max: 3}}
+
+	_, err := repository.Insert(context.Background(), URL{ID: "bob1", Original: "https://bob.example", UserID: "bob"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for index := range errs {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			_, errs[index] = repository.Insert(context.Background(), URL{ID: fmt.Sprintf("alice%d", index), Original: fmt.Sprintf("https://%d.example", index), UserID: "alice"}, limits...)
+		}(index)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, ErrorQuotaExceeded)
+		}
+	}
+	assert.Equal(t, 2, created)
+
+	_, err = repository.Insert(context.Background(), URL{ID: "again", Original: "https://bob.example", UserID: "alice"}, limits...)
+	assert.ErrorIs(t, err, ErrorURLDuplicate)
+
+	urls, err := repository.InsertMany(context.Background(), []URL{{ID: "many1", Original: "https://bob.example", UserID: "alice"}, {ID: "many2", Original: "https://new.example", UserID: "alice"}}, limits...)
+	require.NoError(t, err)
+	assert.Equal(t, "bob1", urls[0].ID)
+	assert.Empty(t, urls[1].ID)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sort fields of user's urls listing.
+const (
+	SortByCreatedAt = "created_at"
+	SortByClicks    = "clicks"
+)
+
+// ListOptions describes page, order and filters of user's urls listing. Zero Limit means all urls,
+// FolderID pointing to zero selects urls outside of any folder, Broken selects urls with broken destination.
+type ListOptions struct {
+	Limit         int
+	Cursor        string
+	SortBy        string
+	Descending    bool
+	Domain        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Search        string
+	Tag           string
+	FolderID      *int64
+	Broken        bool
+}
+
+// ErrorInvalidCursor is error which returning when listing cursor can't be decoded.
+var ErrorInvalidCursor = errors.New("invalid cursor")
+
+// cursor points to last url of previous page by its sort key and id.
+type cursor struct {
+	Value int64  `json:"v"`
+	ID    string `json:"id"`
+}
+
+// sortKey returns value of url field used for ordering, created_at is represented in unix nanoseconds.
+func sortKey(url URL, sortBy string) int64 {
+	if sortBy == SortByClicks {
+		return url.Clicks
+	}
+
+	return url.CreatedAt.UnixNano()
+}
+
+func encodeCursor(url URL, sortBy string) string {
+	b, _ := json.Marshal(cursor{Value: sortKey(url, sortBy), ID: url.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrorInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil || len(c.ID) == 0 {
+		return c, ErrorInvalidCursor
+	}
+
+	return c, nil
+}
+
+// matchesDomain reports whether url host is domain or its subdomain.
+func matchesDomain(original, domain string) bool {
+	u, err := url.Parse(original)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	domain = strings.ToLower(domain)
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// escapeLike escapes LIKE pattern special characters.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -2,24 +2,55 @@ package repository
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/LorezV/url-shorter.git/internal/config"
+	"github.com/LorezV/url-shorter.git/internal/search"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // MemoryRepository is Repository implementation for working with urls in memory and file.
+// Methods are safe for concurrent use, mutex guards all maps.
 type MemoryRepository struct {
-	storage  map[string]URL
-	filePath string
+	mutex     *sync.RWMutex
+	storage   map[string]URL
+	originals map[string]string
+	history   map[string][]URLRevision
+	auditLog  *[]AuditRecord
+	folders   map[int64]Folder
+	folderSeq *int64
+	index     *search.Index
+	variants  map[string]map[int]VariantStats
+	countries map[string]map[string]int64
+	filePath  string
+	// dirty maps urls with not written counters to whether their stats are changed besides clicks.
+	dirty     map[string]bool
+	flushedAt *time.Time
 }
 
+// maxFileLineSize is limit of one line size in file storage.
+const maxFileLineSize = 16 << 20
+
+// Clicks of urls without max clicks and url stats are written to file storage in batches, when count of changed urls or time
+// since last write reaches the limit.
+const (
+	counterFlushSize     = 100
+	counterFlushInterval = 10 * time.Second
+)
+
 // MakeMemoryRepository is constructor for MemoryRepository.
 func MakeMemoryRepository() Repository {
-	var repository = MemoryRepository{storage: make(map[string]URL)}
+	var repository = MemoryRepository{mutex: &sync.RWMutex{}, storage: make(map[string]URL), originals: make(map[string]string), history: make(map[string][]URLRevision), auditLog: &[]AuditRecord{}, folders: make(map[int64]Folder), folderSeq: new(int64), index: search.MakeIndex(), variants: make(map[string]map[int]VariantStats), countries: make(map[string]map[string]int64), dirty: make(map[string]bool), flushedAt: new(time.Time)}
+	*repository.flushedAt = time.Now()
 
 	if len(config.AppConfig.FileStoragePath) > 0 {
 		filePath, err := filepath.Abs(config.AppConfig.FileStoragePath)
@@ -30,12 +61,11 @@ func MakeMemoryRepository() Repository {
 
 		repository.filePath = filePath
 
-		//err = repository.LoadFromFile()
-		//
-		//if err != nil {
-		//	log.Fatalf(err.Error())
-		//	return nil
-		//}
+		err = repository.LoadFromFile()
+		if err != nil {
+			log.Fatalf("Can't load file storage %s: %v", filePath, err)
+			return nil
+		}
 	}
 
 	return repository
@@ -43,114 +73,431 @@ func MakeMemoryRepository() Repository {
 
 // DeleteManyByUser is constructor for MemoryRepository.
 func (r MemoryRepository) DeleteManyByUser(ctx context.Context, urlIDs []string, userID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	var deleted []URL
+
 	for _, id := range urlIDs {
-		if url, ok := r.Get(ctx, id); ok && url.UserID == userID {
+		if url, ok := r.storage[id]; ok && url.UserID == userID && !url.IsDeleted {
 			url.IsDeleted = true
+			url.DeletedAt = &now
+			url.UpdatedAt = now
 			r.storage[url.ID] = url
+			deleted = append(deleted, url)
 		}
 	}
 
+	if err := r.persist(deleted...); err != nil {
+		log.Printf("Can't persist deleted urls: %v", err)
+		return false
+	}
+
 	return true
 }
 
-// LoadFromFile loads urls from file.
-func (r MemoryRepository) LoadFromFile() (err error) {
-	var file *os.File
+// fileRecord is a line of file storage. Line with current state is appended on every change of url or folder, so
+// the last line of url or folder wins on load, purged urls and deleted folders are marked by tombstone line.
+// Audit records and url revisions are appended once, the last stats line of url wins like url line.
+type fileRecord struct {
+	*URL
+	IsDeleted bool            `json:"is_deleted,omitempty"`
+	Purged    bool            `json:"purged,omitempty"`
+	Folder    *folderRecord   `json:"folder,omitempty"`
+	FolderSeq int64           `json:"folder_seq,omitempty"`
+	Audit     *AuditRecord    `json:"audit,omitempty"`
+	Revision  *revisionRecord `json:"revision,omitempty"`
+	Stats     *statsRecord    `json:"stats,omitempty"`
+}
 
-	file, err = os.OpenFile(r.filePath, os.O_RDONLY, 0777)
+// statsRecord is line with all counters of url variants and countries in file storage.
+type statsRecord struct {
+	URLID     string           `json:"url_id"`
+	Variants  []VariantStats   `json:"variants,omitempty"`
+	Countries map[string]int64 `json:"countries,omitempty"`
+}
 
-	defer func() {
-		cerr := file.Close()
-		if err == nil {
-			err = cerr
-		}
-	}()
+// revisionRecord is line of url history in file storage.
+type revisionRecord struct {
+	URLRevision
+	URLID string `json:"url_id"`
+}
 
+// folderRecord is folder line of file storage, folder json doesn't contain its user.
+type folderRecord struct {
+	Folder
+	UserID  string `json:"user_id"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// LoadFromFile loads urls with history and stats, folders and audit log from file storage and compacts it to one line per record. Missing
+// file means empty storage. Urls referring to missing or foreign folder are moved out of folders, deleted urls without
+// deletion time are treated as deleted at load, like postgres migration does.
+func (r MemoryRepository) LoadFromFile() error {
+	file, err := os.Open(r.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return
+		return err
 	}
 
+	defer file.Close()
+
+	records := make(map[string]fileRecord)
+	var order []string
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
 
-	for scanner.Scan() {
-		var url URL
-		err = json.Unmarshal([]byte(scanner.Text()), &url)
+		var record fileRecord
+		if err := json.Unmarshal(text, &record); err != nil {
+			return fmt.Errorf("%s:%d: %w", r.filePath, line, err)
+		}
 
-		if err != nil {
-			return err
+		if record.FolderSeq > *r.folderSeq {
+			*r.folderSeq = record.FolderSeq
+		}
+
+		switch {
+		case record.Audit != nil:
+			*r.auditLog = append(*r.auditLog, *record.Audit)
+		case record.Revision != nil:
+			r.history[record.Revision.URLID] = append(r.history[record.Revision.URLID], record.Revision.URLRevision)
+		case record.Stats != nil:
+			r.setStats(*record.Stats)
+		case record.Folder != nil && record.Folder.Deleted:
+			delete(r.folders, record.Folder.ID)
+		case record.Folder != nil:
+			folder := record.Folder.Folder
+			folder.UserID = record.Folder.UserID
+			r.folders[folder.ID] = folder
+		case record.URL != nil && record.Purged:
+			// History and stats of purged url are dropped, id may be taken by new url later.
+			delete(r.history, record.ID)
+			r.setStats(statsRecord{URLID: record.ID})
+			fallthrough
+		case record.URL != nil:
+			if _, ok := records[record.ID]; !ok {
+				order = append(order, record.ID)
+			}
+			records[record.ID] = record
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for id := range r.folders {
+		if id > *r.folderSeq {
+			*r.folderSeq = id
+		}
+	}
+
+	urls := make([]URL, 0, len(order))
+	loadedAt := time.Now()
+
+	for _, id := range order {
+		record := records[id]
+		if record.Purged {
+			continue
 		}
 
-		if ok := r.Add(context.Background(), url); !ok {
-			return fmt.Errorf("can't pass url in memory")
+		url := *record.URL
+		url.IsDeleted = record.IsDeleted
+		if url.IsDeleted && url.DeletedAt == nil {
+			url.DeletedAt = &loadedAt
 		}
+		if folder, ok := r.folders[url.FolderID]; url.FolderID != 0 && (!ok || folder.UserID != url.UserID) {
+			url.FolderID = 0
+		}
+
+		if !r.add(url) {
+			return fmt.Errorf("can't pass url %s in memory", id)
+		}
+
+		urls = append(urls, r.storage[id])
 	}
 
-	err = scanner.Err()
-	return
+	return r.compact(urls)
 }
 
-// Insert adds row in file storage.
-func (r MemoryRepository) Insert(context context.Context, url URL) (URL, error) {
-	r.Add(context, url)
+// persist appends current state of urls and their changed stats to file storage. Caller must hold mutex.
+func (r MemoryRepository) persist(urls ...URL) error {
+	records := make([]fileRecord, 0, len(urls))
+	for index := range urls {
+		records = append(records, fileRecord{URL: &urls[index], IsDeleted: urls[index].IsDeleted})
 
-	if len(r.filePath) > 0 {
-		file, err := os.OpenFile(r.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0777)
+		if r.dirty[urls[index].ID] {
+			stats := r.statsOf(urls[index].ID)
+			records = append(records, fileRecord{Stats: &stats})
+		}
+		delete(r.dirty, urls[index].ID)
+	}
 
-		if err != nil {
-			return url, err
+	return r.appendRecords(records)
+}
+
+// statsOf returns counters of url as file storage line. Caller must hold mutex.
+func (r MemoryRepository) statsOf(id string) statsRecord {
+	stats := statsRecord{URLID: id}
+	for _, value := range r.variants[id] {
+		stats.Variants = append(stats.Variants, value)
+	}
+
+	sort.Slice(stats.Variants, func(i, j int) bool { return stats.Variants[i].Variant < stats.Variants[j].Variant })
+
+	if len(r.countries[id]) > 0 {
+		stats.Countries = make(map[string]int64, len(r.countries[id]))
+		for country, clicks := range r.countries[id] {
+			stats.Countries[country] = clicks
 		}
+	}
 
-		defer file.Close()
+	return stats
+}
 
-		data, errMarshal := json.Marshal(&url)
-		if errMarshal != nil {
-			return url, errMarshal
+// setStats replaces counters of url by file storage line. Caller must hold mutex.
+func (r MemoryRepository) setStats(stats statsRecord) {
+	delete(r.variants, stats.URLID)
+	for _, value := range stats.Variants {
+		if r.variants[stats.URLID] == nil {
+			r.variants[stats.URLID] = make(map[int]VariantStats)
 		}
+		r.variants[stats.URLID][value.Variant] = value
+	}
 
-		file.Write([]byte(string(data) + "\n"))
+	delete(r.countries, stats.URLID)
+	if len(stats.Countries) > 0 {
+		r.countries[stats.URLID] = stats.Countries
 	}
+}
 
-	return url, nil
+// markDirty remembers that url has not written counters, stats tells whether its stats are changed besides clicks.
+// Caller must hold mutex.
+func (r MemoryRepository) markDirty(id string, stats bool) {
+	r.dirty[id] = r.dirty[id] || stats
 }
 
-// InsertMany adds many rows in file storage.
-func (r MemoryRepository) InsertMany(context context.Context, urls []URL) ([]URL, error) {
-	var (
-		rawData = ""
-		err     error
-	)
+// flushCounters persists urls with not written clicks once batch is full or flush interval is passed, force
+// persists them anyway. Caller must hold mutex.
+func (r MemoryRepository) flushCounters(force bool) error {
+	if len(r.dirty) == 0 || (!force && len(r.dirty) < counterFlushSize && time.Since(*r.flushedAt) < counterFlushInterval) {
+		return nil
+	}
+
+	urls := make([]URL, 0, len(r.dirty))
+	for id := range r.dirty {
+		if url, ok := r.storage[id]; ok {
+			urls = append(urls, url)
+		}
+	}
 
-	for _, url := range urls {
-		r.Add(context, url)
+	err := r.persist(urls...)
 
-		data, err := json.Marshal(&url)
-		if err != nil {
-			return urls, err
+	for id := range r.dirty {
+		delete(r.dirty, id)
+	}
+	*r.flushedAt = time.Now()
+
+	return err
+}
+
+// persistFolders appends current state of folders with folder sequence to file storage. Caller must hold mutex.
+func (r MemoryRepository) persistFolders(deleted bool, folders ...Folder) error {
+	records := make([]fileRecord, len(folders))
+	for index, folder := range folders {
+		records[index] = fileRecord{Folder: &folderRecord{Folder: folder, UserID: folder.UserID, Deleted: deleted}, FolderSeq: *r.folderSeq}
+	}
+
+	return r.appendRecords(records)
+}
+
+// appendRecords appends lines to file storage. Caller must hold mutex.
+func (r MemoryRepository) appendRecords(records []fileRecord) error {
+	if len(r.filePath) == 0 || len(records) == 0 {
+		return nil
+	}
+
+	data, err := marshalRecords(records)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(r.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(data)
+	return err
+}
+
+// compact rewrites file storage with one line per url, url stats, folder, url revision and audit record. Caller must
+// hold mutex.
+func (r MemoryRepository) compact(urls []URL) error {
+	records := make([]fileRecord, 0, len(urls)+len(r.folders)+1)
+
+	if *r.folderSeq > 0 {
+		records = append(records, fileRecord{FolderSeq: *r.folderSeq})
+	}
+
+	folders := make([]Folder, 0, len(r.folders))
+	for _, folder := range r.folders {
+		folders = append(folders, folder)
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].ID < folders[j].ID })
+
+	for _, folder := range folders {
+		records = append(records, fileRecord{Folder: &folderRecord{Folder: folder, UserID: folder.UserID}})
+	}
+
+	for index := range urls {
+		records = append(records, fileRecord{URL: &urls[index], IsDeleted: urls[index].IsDeleted})
+	}
+
+	for index := range urls {
+		for _, revision := range r.history[urls[index].ID] {
+			records = append(records, fileRecord{Revision: &revisionRecord{URLRevision: revision, URLID: urls[index].ID}})
 		}
+	}
+
+	for index := range urls {
+		if stats := r.statsOf(urls[index].ID); len(stats.Variants) > 0 || len(stats.Countries) > 0 {
+			records = append(records, fileRecord{Stats: &stats})
+		}
+	}
 
-		rawData += string(data) + "\n"
+	for index := range *r.auditLog {
+		records = append(records, fileRecord{Audit: &(*r.auditLog)[index]})
 	}
 
-	if len(r.filePath) > 0 {
-		file, err := os.OpenFile(r.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0777)
+	data, err := marshalRecords(records)
+	if err != nil {
+		return err
+	}
+
+	temp := r.filePath + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, r.filePath)
+}
+
+func marshalRecords(records []fileRecord) ([]byte, error) {
+	var buffer bytes.Buffer
 
+	for _, record := range records {
+		data, err := json.Marshal(record)
 		if err != nil {
-			return urls, err
+			return nil, err
+		}
+
+		buffer.Write(data)
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Insert adds row in file storage, url with the same original or id is returned with ErrorURLDuplicate.
+// ErrorQuotaExceeded is returned if url doesn't fit one of limits.
+func (r MemoryRepository) Insert(context context.Context, url URL, limits ...Limit) (URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.getByOriginal(url.Original); ok {
+		return existing, ErrorURLDuplicate
+	}
+
+	if existing, ok := r.storage[url.ID]; ok {
+		return existing, ErrorURLDuplicate
+	}
+
+	if remaining, _ := remainingOf(limits, r.countActive); remaining == 0 {
+		return url, ErrorQuotaExceeded
+	}
+
+	r.add(url)
+
+	if err := r.persist(r.storage[url.ID]); err != nil {
+		return url, err
+	}
+
+	return url, nil
+}
+
+// InsertMany adds many rows in file storage. Urls which don't fit limits aren't inserted and returned as zero URL.
+func (r MemoryRepository) InsertMany(context context.Context, urls []URL, limits ...Limit) ([]URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var inserted []URL
+
+	remaining, _ := remainingOf(limits, r.countActive)
+	accepted := fitLimits(urls, func(original string) bool {
+		_, ok := r.getByOriginal(original)
+		return ok
+	}, remaining)
+
+	for index, url := range urls {
+		if !accepted[index] {
+			urls[index] = URL{}
+			continue
 		}
 
-		defer file.Close()
+		if existing, ok := r.getByOriginal(url.Original); ok {
+			urls[index] = existing
+			continue
+		}
 
-		file.Write([]byte(rawData))
+		r.add(url)
+		inserted = append(inserted, r.storage[url.ID])
 	}
 
-	return urls, err
+	return urls, r.persist(inserted...)
 }
 
 // Add adds url in memory.
 func (r MemoryRepository) Add(context context.Context, url URL) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.add(url)
+}
+
+func (r MemoryRepository) add(url URL) bool {
 	_, ok := r.storage[url.ID]
 	if !ok {
+		if url.Version == 0 {
+			url.Version = 1
+		}
+
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = time.Now()
+		}
+
+		if url.UpdatedAt.IsZero() {
+			url.UpdatedAt = url.CreatedAt
+		}
+
 		r.storage[url.ID] = url
+		r.originals[url.Original] = url.ID
+		r.index.Put(url.ID, searchFields(url)...)
 	}
 
 	return !ok
@@ -158,27 +505,841 @@ func (r MemoryRepository) Add(context context.Context, url URL) bool {
 
 // Get select row by id from file storage.
 func (r MemoryRepository) Get(context context.Context, id string) (URL, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	val, ok := r.storage[id]
 	return val, ok
 }
 
-// GetAllByUser select many rows by user_id from file storage.
-func (r MemoryRepository) GetAllByUser(context context.Context, userID string) ([]URL, error) {
-	result := make([]URL, len(r.storage))
-	i := 0
+// GetAllByUser select page of user's not deleted urls from file storage ordered and filtered by options.
+// Returns cursor of next page or empty string if page is last.
+func (r MemoryRepository) GetAllByUser(context context.Context, userID string, options ListOptions) ([]URL, string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var after *cursor
+
+	if len(options.Cursor) > 0 {
+		c, err := decodeCursor(options.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+
+	position := func(url URL) cursor {
+		return cursor{Value: sortKey(url, options.SortBy), ID: url.ID}
+	}
+
+	compare := func(a, b cursor) int {
+		switch {
+		case a.Value < b.Value:
+			return -1
+		case a.Value > b.Value:
+			return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	}
+
+	search := strings.ToLower(options.Search)
+	result := make([]URL, 0)
 
 	for _, value := range r.storage {
-		if value.UserID == userID {
-			result[i] = value
-			i++
+		if value.UserID != userID || value.IsDeleted {
+			continue
+		}
+
+		if len(options.Domain) > 0 && !matchesDomain(value.Original, options.Domain) {
+			continue
+		}
+
+		if options.CreatedAfter != nil && !value.CreatedAt.After(*options.CreatedAfter) {
+			continue
+		}
+
+		if options.CreatedBefore != nil && !value.CreatedAt.Before(*options.CreatedBefore) {
+			continue
+		}
+
+		if len(search) > 0 && !strings.Contains(strings.ToLower(value.Original), search) {
+			continue
+		}
+
+		if len(options.Tag) > 0 && !hasTag(value.Tags, options.Tag) {
+			continue
+		}
+
+		if options.FolderID != nil && value.FolderID != *options.FolderID {
+			continue
+		}
+
+		if options.Broken && !isBroken(value) {
+			continue
+		}
+
+		if after != nil {
+			c := compare(position(value), *after)
+			if (options.Descending && c >= 0) || (!options.Descending && c <= 0) {
+				continue
+			}
+		}
+
+		result = append(result, value)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		c := compare(position(result[i]), position(result[j]))
+		if options.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if options.Limit > 0 && len(result) > options.Limit {
+		return result[:options.Limit], encodeCursor(result[options.Limit-1], options.SortBy), nil
+	}
+
+	return result, "", nil
+}
+
+// GetByOriginal select row by original url from file storage.
+func (r MemoryRepository) GetByOriginal(context context.Context, original string) (URL, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.getByOriginal(original)
+}
+
+func (r MemoryRepository) getByOriginal(original string) (URL, bool) {
+	id, ok := r.originals[original]
+	if !ok {
+		return URL{}, false
+	}
+
+	url, ok := r.storage[id]
+	return url, ok
+}
+
+// SetDisabled disables or re-enables url by id.
+func (r MemoryRepository) SetDisabled(context context.Context, id string, disabled bool, reason string, status int) (URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, ok := r.storage[id]
+	if !ok {
+		return url, ErrorURLNotFound
+	}
+
+	url.IsDisabled = disabled
+	url.DisabledReason = reason
+	url.DisabledStatus = status
+	url.UpdatedAt = time.Now()
+	r.storage[id] = url
+
+	return url, r.persist(url)
+}
+
+// DeleteAllByUser marks all user's urls as deleted and returns count of affected urls.
+func (r MemoryRepository) DeleteAllByUser(context context.Context, userID string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	var deleted []URL
+
+	for id, url := range r.storage {
+		if url.UserID == userID && !url.IsDeleted {
+			url.IsDeleted = true
+			url.DeletedAt = &now
+			url.UpdatedAt = now
+			r.storage[id] = url
+			deleted = append(deleted, url)
+		}
+	}
+
+	return len(deleted), r.persist(deleted...)
+}
+
+// CountActiveByUsers returns count of not deleted urls owned by any of users.
+func (r MemoryRepository) CountActiveByUsers(context context.Context, userIDs []string) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.countActive(userIDs)
+}
+
+func (r MemoryRepository) countActive(userIDs []string) (int, error) {
+	users := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		users[id] = true
+	}
+
+	count := 0
+
+	for _, url := range r.storage {
+		if users[url.UserID] && !url.IsDeleted {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// InsertAuditRecord adds record in audit log and file storage.
+func (r MemoryRepository) InsertAuditRecord(context context.Context, record AuditRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record.ID = int64(len(*r.auditLog) + 1)
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	if err := r.appendRecords([]fileRecord{{Audit: &record}}); err != nil {
+		return err
+	}
+
+	*r.auditLog = append(*r.auditLog, record)
+	return nil
+}
+
+// GetAuditRecords returns last audit records, newest first.
+func (r MemoryRepository) GetAuditRecords(context context.Context, limit int) ([]AuditRecord, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	records := *r.auditLog
+	if limit <= 0 || limit > len(records) {
+		limit = len(records)
+	}
+
+	result := make([]AuditRecord, limit)
+	for i := 0; i < limit; i++ {
+		result[i] = records[len(records)-1-i]
+	}
+
+	return result, nil
+}
+
+// Update changes destination, redirect type and expiration of user's url if it still has expected version.
+// Zero version skips version check. Previous state is saved to url history.
+func (r MemoryRepository) Update(context context.Context, url URL, version int) (URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current, ok := r.storage[url.ID]
+	if !ok || current.IsDeleted {
+		return url, ErrorURLNotFound
+	}
+
+	if current.UserID != url.UserID {
+		return current, ErrorURLForbidden
+	}
+
+	if version != 0 && current.Version != version {
+		return current, ErrorURLVersionMismatch
+	}
+
+	if id, ok := r.originals[url.Original]; ok && id != url.ID {
+		return current, ErrorURLDuplicate
+	}
+
+	now := time.Now()
+	revision := URLRevision{
+		Version:      current.Version,
+		Original:     current.Original,
+		RedirectType: current.RedirectType,
+		ExpiresAt:    current.ExpiresAt,
+		ReplacedAt:   &now,
+	}
+	if err := r.appendRecords([]fileRecord{{Revision: &revisionRecord{URLRevision: revision, URLID: url.ID}}}); err != nil {
+		return current, err
+	}
+	r.history[url.ID] = append(r.history[url.ID], revision)
+
+	delete(r.originals, current.Original)
+	r.originals[url.Original] = url.ID
+
+	if current.Original != url.Original {
+		current.Health = nil
+		current.Page = nil
+	}
+
+	current.Original = url.Original
+	current.RedirectType = url.RedirectType
+	current.ExpiresAt = url.ExpiresAt
+	current.Title = url.Title
+	current.Description = url.Description
+	current.PasswordHash = url.PasswordHash
+	current.NotBefore = url.NotBefore
+	current.NotAfter = url.NotAfter
+	current.FallbackURL = url.FallbackURL
+	current.Rules = url.Rules
+	if !sameVariants(current.Variants, url.Variants) {
+		delete(r.variants, url.ID)
+		r.markDirty(url.ID, true)
+	}
+	current.Variants = url.Variants
+	current.GeoRules = url.GeoRules
+	current.GeoDefault = url.GeoDefault
+	current.QueryPassthrough = url.QueryPassthrough
+	current.QueryConflict = url.QueryConflict
+	current.UTM = url.UTM
+	current.UpdatedAt = now
+	current.Version++
+	r.storage[url.ID] = current
+	r.index.Put(current.ID, searchFields(current)...)
+
+	return current, r.persist(current)
+}
+
+// GetHistory returns all url versions from oldest to current.
+func (r MemoryRepository) GetHistory(context context.Context, id string) ([]URLRevision, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	current, ok := r.storage[id]
+	if !ok {
+		return nil, ErrorURLNotFound
+	}
+
+	revisions := append([]URLRevision{}, r.history[id]...)
+	revisions = append(revisions, URLRevision{
+		Version:      current.Version,
+		Original:     current.Original,
+		RedirectType: current.RedirectType,
+		ExpiresAt:    current.ExpiresAt,
+	})
+
+	return revisions, nil
+}
+
+// GetDeletedByUser select user's urls deleted since given time, newest deleted first.
+func (r MemoryRepository) GetDeletedByUser(context context.Context, userID string, since time.Time) ([]URL, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []URL
+
+	for _, url := range r.storage {
+		if url.UserID == userID && isDeletedSince(url, since) {
+			result = append(result, url)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletedAt.After(*result[j].DeletedAt)
+	})
+
+	return result, nil
+}
+
+// RestoreManyByUser reverts deletion of user's urls deleted since given time and returns ids of restored urls.
+func (r MemoryRepository) RestoreManyByUser(context context.Context, urlIDs []string, userID string, since time.Time) ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var (
+		restored []string
+		urls     []URL
+	)
+
+	for _, id := range urlIDs {
+		if url, ok := r.storage[id]; ok && url.UserID == userID && isDeletedSince(url, since) {
+			url.IsDeleted = false
+			url.DeletedAt = nil
+			url.UpdatedAt = time.Now()
+			r.storage[id] = url
+			restored = append(restored, id)
+			urls = append(urls, url)
+		}
+	}
+
+	return restored, r.persist(urls...)
+}
+
+// PurgeDeleted removes urls deleted before given time from memory. It's run periodically, so it also writes
+// batched clicks to file storage.
+func (r MemoryRepository) PurgeDeleted(context context.Context, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.flushCounters(true); err != nil {
+		return 0, err
+	}
+
+	var purged []fileRecord
+
+	for id, url := range r.storage {
+		if url.IsDeleted && url.DeletedAt != nil && url.DeletedAt.Before(before) {
+			delete(r.storage, id)
+			delete(r.originals, url.Original)
+			delete(r.history, id)
+			delete(r.variants, id)
+			delete(r.countries, id)
+			r.index.Remove(id)
+			purged = append(purged, fileRecord{URL: &URL{ID: id}, Purged: true})
+		}
+	}
+
+	return len(purged), r.appendRecords(purged)
+}
+
+func isDeletedSince(url URL, since time.Time) bool {
+	return url.IsDeleted && url.DeletedAt != nil && !url.DeletedAt.Before(since)
+}
+
+// IncrementClicks increases clicks counter of url by id, url which reached max clicks returns ErrorURLExhausted.
+func (r MemoryRepository) IncrementClicks(context context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, ok := r.storage[id]
+	if !ok {
+		return ErrorURLNotFound
+	}
+
+	if url.MaxClicks > 0 && url.Clicks >= url.MaxClicks {
+		return ErrorURLExhausted
+	}
+
+	url.Clicks++
+	r.storage[id] = url
+
+	// Only clicks of limited urls must survive restart exactly, others are written in batches.
+	if url.MaxClicks > 0 {
+		return r.persist(url)
+	}
+
+	r.markDirty(id, false)
+
+	return r.flushCounters(false)
+}
+
+// RecordVariantEvent increases counter of event of url variant.
+func (r MemoryRepository) RecordVariantEvent(context context.Context, id string, variant int, event VariantEvent) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.storage[id]; !ok {
+		return ErrorURLNotFound
+	}
+
+	if r.variants[id] == nil {
+		r.variants[id] = make(map[int]VariantStats)
+	}
+
+	stats := r.variants[id][variant]
+	stats.Variant = variant
+
+	switch event {
+	case VariantClick:
+		stats.Clicks++
+	case VariantConversion:
+		stats.Conversions++
+	}
+
+	r.variants[id][variant] = stats
+	r.markDirty(id, true)
+
+	return r.flushCounters(false)
+}
+
+// GetVariantStats returns counters of url variants ordered by variant index.
+func (r MemoryRepository) GetVariantStats(context context.Context, id string) ([]VariantStats, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats := make([]VariantStats, 0, len(r.variants[id]))
+	for _, value := range r.variants[id] {
+		stats = append(stats, value)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Variant < stats[j].Variant })
+
+	return stats, nil
+}
+
+// RecordCountryClick increases clicks counter of url from country.
+func (r MemoryRepository) RecordCountryClick(context context.Context, id string, country string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.storage[id]; !ok {
+		return ErrorURLNotFound
+	}
+
+	if r.countries[id] == nil {
+		r.countries[id] = make(map[string]int64)
+	}
+
+	r.countries[id][country]++
+	r.markDirty(id, true)
+
+	return r.flushCounters(false)
+}
+
+// GetCountryStats returns clicks of url by country ordered from most clicked.
+func (r MemoryRepository) GetCountryStats(context context.Context, id string) ([]CountryStats, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats := make([]CountryStats, 0, len(r.countries[id]))
+	for country, clicks := range r.countries[id] {
+		stats = append(stats, CountryStats{Country: country, Clicks: clicks})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Clicks != stats[j].Clicks {
+			return stats[i].Clicks > stats[j].Clicks
+		}
+		return stats[i].Country < stats[j].Country
+	})
+
+	return stats, nil
+}
+
+// GetDueHealthChecks returns active urls which were never checked or were checked before given time,
+// never checked and least recently checked first. Zero limit means all urls.
+func (r MemoryRepository) GetDueHealthChecks(context context.Context, checkedBefore time.Time, limit int) ([]URL, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]URL, 0)
+
+	for _, url := range r.storage {
+		if url.IsDeleted || url.IsDisabled || (url.Health != nil && !url.Health.CheckedAt.Before(checkedBefore)) {
+			continue
+		}
+
+		result = append(result, url)
+	}
+
+	checkedAt := func(url URL) time.Time {
+		if url.Health == nil {
+			return time.Time{}
 		}
+		return url.Health.CheckedAt
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := checkedAt(result[i]), checkedAt(result[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// SetHealth saves result of destination check of url, result is dropped if destination changed since check started.
+func (r MemoryRepository) SetHealth(context context.Context, id string, original string, health Health) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, ok := r.storage[id]
+	if !ok {
+		return ErrorURLNotFound
+	}
+
+	if url.Original != original {
+		return nil
+	}
+
+	url.Health = &health
+	r.storage[id] = url
+
+	return r.persist(url)
+}
+
+// SetPage saves fetched metadata of url destination page, metadata is dropped if destination changed since fetch started.
+func (r MemoryRepository) SetPage(context context.Context, id string, original string, page Page) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, ok := r.storage[id]
+	if !ok {
+		return ErrorURLNotFound
+	}
+
+	if url.Original != original {
+		return nil
 	}
 
-	return result[:i], nil
+	url.Page = &page
+	r.storage[id] = url
+
+	return r.persist(url)
 }
 
-// Close prints close
+// Close writes batched clicks to file storage.
 func (r MemoryRepository) Close() error {
 	fmt.Println("Close memory repository")
-	return nil
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.flushCounters(true)
+}
+
+// getOwn returns user's not deleted url by id.
+func (r MemoryRepository) getOwn(id string, userID string) (URL, error) {
+	url, ok := r.storage[id]
+	if !ok || url.IsDeleted {
+		return url, ErrorURLNotFound
+	}
+
+	if url.UserID != userID {
+		return url, ErrorURLForbidden
+	}
+
+	return url, nil
+}
+
+// SetTags replaces tags of user's url.
+func (r MemoryRepository) SetTags(context context.Context, id string, userID string, tags []string) (URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, err := r.getOwn(id, userID)
+	if err != nil {
+		return url, err
+	}
+
+	url.Tags = append([]string(nil), tags...)
+	sort.Strings(url.Tags)
+	url.UpdatedAt = time.Now()
+	r.storage[id] = url
+	r.index.Put(id, searchFields(url)...)
+
+	return url, r.persist(url)
+}
+
+// GetTagsByUser returns tags of user's not deleted urls with usage count ordered by name.
+func (r MemoryRepository) GetTagsByUser(context context.Context, userID string) ([]TagUsage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	counts := make(map[string]int)
+
+	for _, url := range r.storage {
+		if url.UserID == userID && !url.IsDeleted {
+			for _, tag := range url.Tags {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]TagUsage, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, TagUsage{Name: name, Count: count})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+// RenameTag replaces tag on all user's urls and returns count of affected urls.
+func (r MemoryRepository) RenameTag(context context.Context, userID string, from string, to string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.replaceTag(userID, from, to)
+}
+
+// DeleteTag removes tag from all user's urls and returns count of affected urls.
+func (r MemoryRepository) DeleteTag(context context.Context, userID string, tag string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.replaceTag(userID, tag, "")
+}
+
+// replaceTag removes tag from user's urls and adds replacement if it is not empty.
+func (r MemoryRepository) replaceTag(userID string, tag string, replacement string) (int, error) {
+	var changed []URL
+
+	for id, url := range r.storage {
+		if url.UserID != userID || !hasTag(url.Tags, tag) {
+			continue
+		}
+
+		tags := make([]string, 0, len(url.Tags))
+		for _, value := range url.Tags {
+			if value != tag && value != replacement {
+				tags = append(tags, value)
+			}
+		}
+
+		if len(replacement) > 0 {
+			tags = append(tags, replacement)
+			sort.Strings(tags)
+		}
+
+		url.Tags = tags
+		url.UpdatedAt = time.Now()
+		r.storage[id] = url
+		r.index.Put(id, searchFields(url)...)
+		changed = append(changed, url)
+	}
+
+	return len(changed), r.persist(changed...)
+}
+
+// InsertFolder adds folder in memory.
+func (r MemoryRepository) InsertFolder(context context.Context, folder Folder) (Folder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	*r.folderSeq++
+	folder.ID = *r.folderSeq
+	folder.CreatedAt = time.Now()
+	r.folders[folder.ID] = folder
+
+	return folder, r.persistFolders(false, folder)
+}
+
+// GetFolder returns user's folder by id.
+func (r MemoryRepository) GetFolder(context context.Context, id int64, userID string) (Folder, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.getFolder(id, userID)
+}
+
+func (r MemoryRepository) getFolder(id int64, userID string) (Folder, error) {
+	folder, ok := r.folders[id]
+	if !ok || folder.UserID != userID {
+		return folder, ErrorFolderNotFound
+	}
+
+	return folder, nil
+}
+
+// GetFoldersByUser returns all user's folders ordered by id.
+func (r MemoryRepository) GetFoldersByUser(context context.Context, userID string) ([]Folder, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	folders := make([]Folder, 0)
+
+	for _, folder := range r.folders {
+		if folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].ID < folders[j].ID
+	})
+
+	return folders, nil
+}
+
+// UpdateFolder changes name and parent of user's folder.
+func (r MemoryRepository) UpdateFolder(context context.Context, folder Folder) (Folder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current, err := r.getFolder(folder.ID, folder.UserID)
+	if err != nil {
+		return current, err
+	}
+
+	current.Name = folder.Name
+	current.ParentID = folder.ParentID
+	r.folders[current.ID] = current
+
+	return current, r.persistFolders(false, current)
+}
+
+// DeleteFolder removes user's folder, its subfolders and urls are moved to parent folder.
+func (r MemoryRepository) DeleteFolder(context context.Context, id int64, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	folder, err := r.getFolder(id, userID)
+	if err != nil {
+		return err
+	}
+
+	var children []Folder
+
+	for childID, child := range r.folders {
+		if child.ParentID == id {
+			child.ParentID = folder.ParentID
+			r.folders[childID] = child
+			children = append(children, child)
+		}
+	}
+
+	var moved []URL
+
+	for urlID, url := range r.storage {
+		if url.FolderID == id {
+			url.FolderID = folder.ParentID
+			r.storage[urlID] = url
+			moved = append(moved, url)
+		}
+	}
+
+	delete(r.folders, id)
+
+	if err := r.persistFolders(false, children...); err != nil {
+		return err
+	}
+	if err := r.persist(moved...); err != nil {
+		return err
+	}
+
+	return r.persistFolders(true, folder)
+}
+
+// SetFolder moves user's url to folder, zero folderID moves it out of any folder.
+func (r MemoryRepository) SetFolder(context context.Context, id string, userID string, folderID int64) (URL, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	url, err := r.getOwn(id, userID)
+	if err != nil {
+		return url, err
+	}
+
+	url.FolderID = folderID
+	url.UpdatedAt = time.Now()
+	r.storage[id] = url
+
+	return url, r.persist(url)
+}
+
+// Search returns user's not deleted urls matching query by id, title, tags and original url ordered by rank,
+// zero limit means unlimited.
+func (r MemoryRepository) Search(context context.Context, userID string, query string, limit int) ([]SearchHit, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	hits := make([]SearchHit, 0)
+
+	for _, hit := range r.index.Search(query) {
+		url, ok := r.storage[hit.ID]
+		if !ok || url.UserID != userID || url.IsDeleted {
+			continue
+		}
+
+		hits = append(hits, SearchHit{URL: url, Rank: hit.Score})
+		if limit > 0 && len(hits) == limit {
+			break
+		}
+	}
+
+	return hits, nil
 }
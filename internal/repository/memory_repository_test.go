@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openFileRepository makes memory repository with file storage by path like the app does on start.
+func openFileRepository(t *testing.T, path string) MemoryRepository {
+	config.AppConfig.FileStoragePath = path
+	defer func() { config.AppConfig.FileStoragePath = "" }()
+
+	return MakeMemoryRepository().(MemoryRepository)
+}
+
+func TestLoadFromFileLegacyLines(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	lines := `{"id":"legacy","original_url":"https://example.com/old","short_url":"http://127.0.0.1:8080/legacy","user_id":"user01"}
+{"id":"recent","original_url":"https://example.com/new","short_url":"http://127.0.0.1:8080/recent","user_id":"user01","created_at":"2023-05-01T10:00:00Z","title":"New"}
+`
+	require.NoError(t, os.WriteFile(filePath, []byte(lines), 0600))
+
+	repository := openFileRepository(t, filePath)
+
+	legacy, ok := repository.storage["legacy"]
+	require.True(t, ok)
+	assert.Equal(t, 1, legacy.Version)
+	assert.False(t, legacy.CreatedAt.IsZero())
+	assert.Equal(t, legacy.CreatedAt, legacy.UpdatedAt)
+
+	recent, ok := repository.storage["recent"]
+	require.True(t, ok)
+	assert.Equal(t, "New", recent.Title)
+	assert.Equal(t, "2023-05-01T10:00:00Z", recent.UpdatedAt.Format(time.RFC3339))
+
+	// Legacy lines are rewritten with filled fields.
+	reopened := openFileRepository(t, filePath)
+	assert.Equal(t, legacy.CreatedAt.Unix(), reopened.storage["legacy"].CreatedAt.Unix())
+}
+
+func TestFileStoragePersistsChanges(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+
+	repository := openFileRepository(t, filePath)
+	_, err := repository.InsertMany(ctx, []URL{
+		{ID: "kept", Original: "https://example.com/kept", UserID: "user01"},
+		{ID: "gone", Original: "https://example.com/gone", UserID: "user01"},
+		{ID: "trash", Original: "https://example.com/trash", UserID: "user01"},
+	})
+	require.NoError(t, err)
+
+	_, err = repository.Update(ctx, URL{ID: "kept", Original: "https://example.com/moved", UserID: "user01"}, 0)
+	require.NoError(t, err)
+	require.NoError(t, repository.IncrementClicks(ctx, "kept"))
+	_, err = repository.SetTags(ctx, "kept", "user01", []string{"docs"})
+	require.NoError(t, err)
+	require.NoError(t, repository.SetPage(ctx, "kept", "https://example.com/moved", Page{Title: "Moved"}))
+
+	repository.DeleteManyByUser(ctx, []string{"gone", "trash"}, "user01")
+	_, err = repository.PurgeDeleted(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	_, err = repository.Insert(ctx, URL{ID: "trash", Original: "https://example.com/trash", UserID: "user01"})
+	require.NoError(t, err)
+	repository.DeleteManyByUser(ctx, []string{"trash"}, "user01")
+
+	reopened := openFileRepository(t, filePath)
+
+	kept, ok := reopened.storage["kept"]
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/moved", kept.Original)
+	assert.Equal(t, 2, kept.Version)
+	assert.Equal(t, int64(1), kept.Clicks)
+	assert.Equal(t, []string{"docs"}, kept.Tags)
+	assert.Equal(t, "Moved", kept.Page.Title)
+	assert.True(t, kept.UpdatedAt.After(kept.CreatedAt))
+
+	_, ok = reopened.storage["gone"]
+	assert.False(t, ok)
+
+	trash, ok := reopened.storage["trash"]
+	require.True(t, ok)
+	assert.True(t, trash.IsDeleted)
+	assert.NotNil(t, trash.DeletedAt)
+
+	_, found := reopened.GetByOriginal(ctx, "https://example.com/moved")
+	assert.True(t, found)
+
+	revisions, err := reopened.GetHistory(ctx, "kept")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "https://example.com/kept", revisions[0].Original)
+	assert.NotNil(t, revisions[0].ReplacedAt)
+	assert.Equal(t, "https://example.com/moved", revisions[1].Original)
+
+	// One line per url and one per revision.
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+}
+
+func TestFileStoragePersistsFolders(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+
+	repository := openFileRepository(t, filePath)
+	docs, err := repository.InsertFolder(ctx, Folder{UserID: "user01", Name: "Docs"})
+	require.NoError(t, err)
+	guides, err := repository.InsertFolder(ctx, Folder{UserID: "user01", Name: "Guides", ParentID: docs.ID})
+	require.NoError(t, err)
+	removed, err := repository.InsertFolder(ctx, Folder{UserID: "user01", Name: "Removed"})
+	require.NoError(t, err)
+
+	_, err = repository.UpdateFolder(ctx, Folder{ID: guides.ID, UserID: "user01", Name: "Manuals", ParentID: docs.ID})
+	require.NoError(t, err)
+
+	_, err = repository.Insert(ctx, URL{ID: "filed", Original: "https://example.com/filed", UserID: "user01"})
+	require.NoError(t, err)
+	_, err = repository.SetFolder(ctx, "filed", "user01", guides.ID)
+	require.NoError(t, err)
+	require.NoError(t, repository.DeleteFolder(ctx, removed.ID, "user01"))
+
+	reopened := openFileRepository(t, filePath)
+
+	folders, err := reopened.GetFoldersByUser(ctx, "user01")
+	require.NoError(t, err)
+	require.Len(t, folders, 2)
+	assert.Equal(t, "Docs", folders[0].Name)
+	assert.Equal(t, "Manuals", folders[1].Name)
+	assert.Equal(t, docs.ID, folders[1].ParentID)
+	assert.Equal(t, guides.ID, reopened.storage["filed"].FolderID)
+
+	// Ids of deleted folders aren't reused after restart.
+	folder, err := reopened.InsertFolder(ctx, Folder{UserID: "stranger01", Name: "Mine"})
+	require.NoError(t, err)
+	assert.Equal(t, removed.ID+1, folder.ID)
+}
+
+func TestLoadFromFileDanglingFolders(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	lines := `{"folder":{"id":2,"name":"Foreign","created_at":"2023-05-01T10:00:00Z","user_id":"stranger01"}}
+{"id":"lost","original_url":"https://example.com/lost","user_id":"user01","folder_id":1}
+{"id":"foreign","original_url":"https://example.com/foreign","user_id":"user01","folder_id":2}
+`
+	require.NoError(t, os.WriteFile(filePath, []byte(lines), 0600))
+
+	repository := openFileRepository(t, filePath)
+
+	assert.Zero(t, repository.storage["lost"].FolderID)
+	assert.Zero(t, repository.storage["foreign"].FolderID)
+
+	folder, err := repository.InsertFolder(context.Background(), Folder{UserID: "user01", Name: "New"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), folder.ID)
+}
+
+func TestFileStorageBatchesClicks(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+
+	repository := openFileRepository(t, filePath)
+	_, err := repository.InsertMany(ctx, []URL{
+		{ID: "popular", Original: "https://example.com/popular", UserID: "user01"},
+		{ID: "limited", Original: "https://example.com/limited", UserID: "user01", MaxClicks: 5},
+	})
+	require.NoError(t, err)
+
+	lines := func() int {
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		return strings.Count(string(data), "\n")
+	}
+
+	for index := 0; index < 3; index++ {
+		require.NoError(t, repository.IncrementClicks(ctx, "popular"))
+	}
+	assert.Equal(t, 2, lines())
+
+	// Clicks of limited url are written at once, they enforce max clicks after restart.
+	require.NoError(t, repository.IncrementClicks(ctx, "limited"))
+	require.NoError(t, repository.IncrementClicks(ctx, "limited"))
+	assert.Equal(t, 4, lines())
+
+	_, err = repository.PurgeDeleted(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 5, lines())
+
+	require.NoError(t, repository.IncrementClicks(ctx, "popular"))
+	require.NoError(t, repository.Close())
+
+	reopened := openFileRepository(t, filePath)
+	assert.Equal(t, int64(4), reopened.storage["popular"].Clicks)
+	assert.Equal(t, int64(2), reopened.storage["limited"].Clicks)
+}
+
+func TestLoadFromFileLegacyDeleted(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	lines := `{"id":"legacy","original_url":"https://example.com/old","user_id":"user01","is_deleted":true}
+`
+	require.NoError(t, os.WriteFile(filePath, []byte(lines), 0600))
+
+	repository := openFileRepository(t, filePath)
+
+	// Legacy deleted url gets full grace period from load, like postgres migration backfills deleted_at.
+	legacy := repository.storage["legacy"]
+	require.NotNil(t, legacy.DeletedAt)
+
+	count, err := repository.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	reopened := openFileRepository(t, filePath)
+	require.NotNil(t, reopened.storage["legacy"].DeletedAt)
+	assert.True(t, legacy.DeletedAt.Equal(*reopened.storage["legacy"].DeletedAt))
+
+	count, err = reopened.PurgeDeleted(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestFileStoragePersistsAuditLog(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+
+	repository := openFileRepository(t, filePath)
+	require.NoError(t, repository.InsertAuditRecord(ctx, AuditRecord{Actor: "admin", Action: "url.disable", Target: "abuse1", Details: "spam"}))
+	require.NoError(t, repository.InsertAuditRecord(ctx, AuditRecord{Actor: "admin", Action: "url.enable", Target: "abuse1"}))
+
+	reopened := openFileRepository(t, filePath)
+	records, err := reopened.GetAuditRecords(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "url.enable", records[0].Action)
+	assert.Equal(t, int64(2), records[0].ID)
+	assert.Equal(t, "spam", records[1].Details)
+
+	require.NoError(t, reopened.InsertAuditRecord(ctx, AuditRecord{Actor: "admin", Action: "url.lookup", Target: "abuse1"}))
+	records, err = openFileRepository(t, filePath).GetAuditRecords(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), records[0].ID)
+}
+
+func TestFileStoragePersistsVariantStats(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	variants := []Variant{{Destination: "https://example.com/a", Weight: 1}, {Destination: "https://example.com/b", Weight: 1}}
+
+	repository := openFileRepository(t, filePath)
+	_, err := repository.InsertMany(ctx, []URL{
+		{ID: "split", Original: "https://example.com/split", UserID: "user01", Variants: variants},
+		{ID: "reset", Original: "https://example.com/reset", UserID: "user01", Variants: variants},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repository.RecordVariantEvent(ctx, "split", 0, VariantClick))
+	require.NoError(t, repository.RecordVariantEvent(ctx, "split", 1, VariantClick))
+	require.NoError(t, repository.RecordVariantEvent(ctx, "split", 1, VariantConversion))
+	require.NoError(t, repository.RecordVariantEvent(ctx, "reset", 0, VariantClick))
+	require.NoError(t, repository.Close())
+
+	_, err = repository.Update(ctx, URL{ID: "reset", Original: "https://example.com/reset", UserID: "user01", Variants: variants[:1]}, 0)
+	require.NoError(t, err)
+
+	reopened := openFileRepository(t, filePath)
+
+	stats, err := reopened.GetVariantStats(ctx, "split")
+	require.NoError(t, err)
+	assert.Equal(t, []VariantStats{{Variant: 0, Clicks: 1}, {Variant: 1, Clicks: 1, Conversions: 1}}, stats)
+
+	// Stats reset by change of variants stay reset after restart.
+	stats, err = reopened.GetVariantStats(ctx, "reset")
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	stats, err = openFileRepository(t, filePath).GetVariantStats(ctx, "split")
+	require.NoError(t, err)
+	assert.Len(t, stats, 2)
+}
+
+func TestFileStoragePersistsCountryStats(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+
+	repository := openFileRepository(t, filePath)
+	_, err := repository.Insert(ctx, URL{ID: "geo", Original: "https://example.com/geo", UserID: "user01"})
+	require.NoError(t, err)
+
+	for _, country := range []string{"DE", "FR", "DE", ""} {
+		require.NoError(t, repository.RecordCountryClick(ctx, "geo", country))
+	}
+	require.NoError(t, repository.Close())
+
+	expected := []CountryStats{{Country: "DE", Clicks: 2}, {Country: "", Clicks: 1}, {Country: "FR", Clicks: 1}}
+
+	stats, err := openFileRepository(t, filePath).GetCountryStats(ctx, "geo")
+	require.NoError(t, err)
+	assert.Equal(t, expected, stats)
+
+	stats, err = openFileRepository(t, filePath).GetCountryStats(ctx, "geo")
+	require.NoError(t, err)
+	assert.Equal(t, expected, stats)
+}
+
+func TestLoadFromFileMissing(t *testing.T) {
+	repository := openFileRepository(t, filepath.Join(t.TempDir(), "missing.json"))
+	assert.Empty(t, repository.storage)
+}
+
+func TestMemorySearchLimit(t *testing.T) {
+	ctx := context.Background()
+	repository := MakeMemoryRepository()
+
+	for _, id := range []string{"docs01", "docs02", "docs03"} {
+		_, err := repository.Insert(ctx, URL{ID: id, Original: "https://example.com/" + id, UserID: "user01", Title: "Pricing docs"})
+		require.NoError(t, err)
+	}
+
+	hits, err := repository.Search(ctx, "user01", "pricing", 0)
+	require.NoError(t, err)
+	assert.Len(t, hits, 3)
+
+	hits, err = repository.Search(ctx, "user01", "pricing", 1)
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "time"
+
+// Page is metadata of url destination page, it's fetched in background after url is created or its destination changes.
+type Page struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
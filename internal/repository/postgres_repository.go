@@ -3,15 +3,129 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/LorezV/url-shorter.git/internal/config"
 	"github.com/jackc/pgerrcode"
 	"log"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 )
 
+// urlColumns is a list of url table columns in order expected by scanURL, tags are aggregated from url_tag table.
+const urlColumns = `id, short, original, user_id, is_deleted, is_disabled, disabled_reason, disabled_status, redirect_type, expires_at, version, deleted_at, created_at, updated_at, clicks, max_clicks, not_before, not_after, fallback_url, rules, variants, geo_rules, geo_default, query_passthrough, query_conflict, utm, health, page, creator_ip, creator_user_agent, title, description, folder_id, password_hash,
+	COALESCE((SELECT string_agg(tag, ',' ORDER BY tag) FROM url_tag WHERE url_tag.url_id=url.id), '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scannerFunc adapts function to rowScanner, it allows to scan extra columns after url columns.
+type scannerFunc func(dest ...interface{}) error
+
+func (f scannerFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+func scanURL(row rowScanner) (URL, error) {
+	var (
+		url      URL
+		folderID sql.NullInt64
+		tags     string
+		rules    []byte
+		variants []byte
+		geoRules []byte
+		utm      []byte
+		health   []byte
+		page     []byte
+	)
+
+	err := row.Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted, &url.IsDisabled, &url.DisabledReason, &url.DisabledStatus, &url.RedirectType, &url.ExpiresAt, &url.Version, &url.DeletedAt, &url.CreatedAt, &url.UpdatedAt, &url.Clicks, &url.MaxClicks, &url.NotBefore, &url.NotAfter, &url.FallbackURL, &rules, &variants, &geoRules, &url.GeoDefault, &url.QueryPassthrough, &url.QueryConflict, &utm, &health, &page, &url.CreatorIP, &url.CreatorUserAgent, &url.Title, &url.Description, &folderID, &url.PasswordHash, &tags)
+
+	url.FolderID = folderID.Int64
+	if len(tags) > 0 {
+		url.Tags = strings.Split(tags, ",")
+	}
+
+	if err == nil && len(rules) > 0 {
+		err = json.Unmarshal(rules, &url.Rules)
+	}
+
+	if err == nil && len(variants) > 0 {
+		err = json.Unmarshal(variants, &url.Variants)
+	}
+
+	if err == nil && len(geoRules) > 0 {
+		err = json.Unmarshal(geoRules, &url.GeoRules)
+	}
+
+	if err == nil && len(utm) > 0 {
+		err = json.Unmarshal(utm, &url.UTM)
+		if len(url.UTM) == 0 {
+			url.UTM = nil
+		}
+	}
+
+	if err == nil && len(health) > 0 {
+		err = json.Unmarshal(health, &url.Health)
+	}
+
+	if err == nil && len(page) > 0 {
+		err = json.Unmarshal(page, &url.Page)
+	}
+
+	return url, err
+}
+
+// jsonArray formats values for jsonb column, nil slice is stored as empty array.
+func jsonArray[T any](values []T) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+
+	value, _ := json.Marshal(values)
+
+	return string(value)
+}
+
+// tagsText is expression of url table which aggregates tags of row from url_tag table for full-text search.
+const tagsText = `COALESCE((SELECT string_agg(tag, ' ' ORDER BY tag) FROM url_tag WHERE url_tag.url_id=url.id), '')`
+
+// jsonObject formats map for jsonb column, nil map is stored as empty object.
+func jsonObject(values map[string]string) string {
+	if len(values) == 0 {
+		return "{}"
+	}
+
+	value, _ := json.Marshal(values)
+
+	return string(value)
+}
+
+// nullableID converts zero id to NULL.
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// textArray formats values as postgresql array literal.
+func textArray(values []string) string {
+	quoted := make([]string, len(values))
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	for index, value := range values {
+		quoted[index] = `"` + replacer.Replace(value) + `"`
+	}
+
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
 // PostgresRepository is Repository implementation for working with postgesql database.
 type PostgresRepository struct {
 	database *sql.DB
@@ -49,6 +163,95 @@ CREATE TABLE IF NOT EXISTS "url" (
 	"user_id" VARCHAR(12) NULL DEFAULT NULL,
 	"is_deleted" BOOLEAN NOT NULL DEFAULT FALSE,
 	PRIMARY KEY ("id")
+);
+ALTER TABLE "url" ALTER COLUMN "original" TYPE TEXT;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "is_disabled" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "disabled_reason" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "disabled_status" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "redirect_type" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "expires_at" TIMESTAMPTZ NULL DEFAULT NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ NULL DEFAULT NULL;
+UPDATE "url" SET "deleted_at"=NOW() WHERE "is_deleted" AND "deleted_at" IS NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "clicks" BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "creator_ip" VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "creator_user_agent" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "title" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "description" TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS "folder" (
+	"id" BIGSERIAL NOT NULL,
+	"user_id" VARCHAR(12) NOT NULL,
+	"name" VARCHAR(128) NOT NULL,
+	"parent_id" BIGINT NULL DEFAULT NULL REFERENCES "folder" ("id"),
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "folder_user_idx" ON "folder" ("user_id");
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "folder_id" BIGINT NULL DEFAULT NULL REFERENCES "folder" ("id");
+CREATE TABLE IF NOT EXISTS "url_tag" (
+	"url_id" VARCHAR(12) NOT NULL,
+	"tag" VARCHAR(64) NOT NULL,
+	PRIMARY KEY ("url_id", "tag")
+);
+CREATE INDEX IF NOT EXISTS "url_tag_tag_idx" ON "url_tag" ("tag");
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "tags_text" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "password_hash" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "max_clicks" BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "not_before" TIMESTAMPTZ NULL DEFAULT NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "not_after" TIMESTAMPTZ NULL DEFAULT NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "fallback_url" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "rules" JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "variants" JSONB NOT NULL DEFAULT '[]';
+CREATE TABLE IF NOT EXISTS "url_variant_stat" (
+	"url_id" VARCHAR(12) NOT NULL,
+	"variant" INTEGER NOT NULL,
+	"clicks" BIGINT NOT NULL DEFAULT 0,
+	"conversions" BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY ("url_id", "variant")
+);
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "geo_rules" JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "geo_default" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "query_passthrough" BOOLEAN NULL DEFAULT NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "query_conflict" VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "utm" JSONB NOT NULL DEFAULT '{}';
+CREATE TABLE IF NOT EXISTS "url_country_stat" (
+	"url_id" VARCHAR(12) NOT NULL,
+	"country" VARCHAR(2) NOT NULL,
+	"clicks" BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY ("url_id", "country")
+);
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "health" JSONB NULL DEFAULT NULL;
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "health_checked_at" TIMESTAMPTZ NULL DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS "url_health_checked_idx" ON "url" ("health_checked_at" NULLS FIRST, "id");
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "page" JSONB NULL DEFAULT NULL;
+UPDATE "url" SET "tags_text"=`+tagsText+` WHERE "tags_text"='' AND EXISTS (SELECT 1 FROM "url_tag" WHERE "url_tag"."url_id"="url"."id");
+ALTER TABLE "url" ADD COLUMN IF NOT EXISTS "search_vector" TSVECTOR GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', regexp_replace("id", '[^[:alnum:]]+', ' ', 'g')), 'A') ||
+	setweight(to_tsvector('simple', regexp_replace("title" || ' ' || "tags_text", '[^[:alnum:]]+', ' ', 'g')), 'B') ||
+	setweight(to_tsvector('simple', regexp_replace(regexp_replace("original", '^[a-z]+://(www\.)?', ''), '[^[:alnum:]]+', ' ', 'g')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS "url_search_idx" ON "url" USING GIN ("search_vector");
+CREATE INDEX IF NOT EXISTS "url_user_created_idx" ON "url" ("user_id", "created_at", "id");
+CREATE INDEX IF NOT EXISTS "url_user_clicks_idx" ON "url" ("user_id", "clicks", "id");
+CREATE TABLE IF NOT EXISTS "url_history" (
+	"url_id" VARCHAR(12) NOT NULL,
+	"version" INTEGER NOT NULL,
+	"original" TEXT NOT NULL,
+	"redirect_type" INTEGER NOT NULL DEFAULT 0,
+	"expires_at" TIMESTAMPTZ NULL DEFAULT NULL,
+	"replaced_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("url_id", "version")
+);
+CREATE TABLE IF NOT EXISTS "audit_log" (
+	"id" BIGSERIAL NOT NULL,
+	"actor" VARCHAR(128) NOT NULL,
+	"action" VARCHAR(64) NOT NULL,
+	"target" VARCHAR(256) NOT NULL,
+	"details" TEXT NOT NULL DEFAULT '',
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("id")
 );`)
 
 	if seedError != nil {
@@ -58,14 +261,38 @@ CREATE TABLE IF NOT EXISTS "url" (
 	return repository
 }
 
-// Insert adds row in url database table.
-func (r PostgresRepository) Insert(ctx context.Context, url URL) (URL, error) {
-	_, err := r.database.ExecContext(ctx, `INSERT INTO url (id, short, original, user_id) VALUES ($1, $2, $3, $4);`, url.ID, url.Short, url.Original, url.UserID)
+// insertColumns is a list of url table columns filled on insert, values are made by insertValues.
+const insertColumns = `id, short, original, user_id, redirect_type, expires_at, created_at, updated_at, creator_ip, creator_user_agent, title, description, folder_id, tags_text, password_hash, max_clicks, not_before, not_after, fallback_url, rules, variants, geo_rules, geo_default, query_passthrough, query_conflict, utm`
+
+// insertChunkSize is max count of rows inserted by one statement, postgresql limits count of statement parameters.
+const insertChunkSize = 1000
+
+// insertValues returns parameters of url row for insertColumns, created_at is used for updated_at too.
+func insertValues(url URL) []interface{} {
+	return []interface{}{url.ID, url.Short, url.Original, url.UserID, url.RedirectType, url.ExpiresAt, url.CreatedAt, url.CreatorIP, url.CreatorUserAgent, url.Title, url.Description, nullableID(url.FolderID), strings.Join(url.Tags, " "), url.PasswordHash, url.MaxClicks, url.NotBefore, url.NotAfter, url.FallbackURL, jsonArray(url.Rules), jsonArray(url.Variants), jsonArray(url.GeoRules), url.GeoDefault, url.QueryPassthrough, url.QueryConflict, jsonObject(url.UTM)}
+}
+
+// insertPlaceholders returns row of insertColumns placeholders for values starting after offset parameter.
+func insertPlaceholders(offset int) string {
+	placeholders := make([]string, 0, 26)
+	for index := 1; index <= 25; index++ {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", offset+index))
+		if index == 7 {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", offset+index))
+		}
+	}
+
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// Insert adds row in url database table. Url with the same original or id is returned with ErrorURLDuplicate,
+// ErrorQuotaExceeded is returned if url doesn't fit one of limits.
+func (r PostgresRepository) Insert(ctx context.Context, url URL, limits ...Limit) (URL, error) {
+	err := r.insert(ctx, url, limits)
 
 	if err != nil {
-		if strings.Contains(err.Error(), pgerrcode.UniqueViolation) {
-			var dbURL URL
-			err := r.database.QueryRowContext(ctx, `SELECT id, short, original, user_id, is_deleted FROM url WHERE id=$1 OR original=$2;`, url.ID, url.Original).Scan(&dbURL.ID, &dbURL.Short, &dbURL.Original, &dbURL.UserID, &dbURL.IsDeleted)
+		if errors.Is(err, ErrorURLDuplicate) || strings.Contains(err.Error(), pgerrcode.UniqueViolation) {
+			dbURL, err := scanURL(r.database.QueryRowContext(ctx, `SELECT `+urlColumns+` FROM url WHERE id=$1 OR original=$2;`, url.ID, url.Original))
 			if err != nil {
 				return url, err
 			}
@@ -78,45 +305,231 @@ func (r PostgresRepository) Insert(ctx context.Context, url URL) (URL, error) {
 	return url, nil
 }
 
-// InsertMany adds many rows in url database table.
-func (r PostgresRepository) InsertMany(ctx context.Context, urls []URL) ([]URL, error) {
-	tx, err := r.database.Begin()
+// insert adds row with tags, limits are checked in transaction under locks of their users.
+func (r PostgresRepository) insert(ctx context.Context, url URL, limits []Limit) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO url (` + insertColumns + `)
+			VALUES ` + insertPlaceholders(0) + `
+			RETURNING id
+		)
+		INSERT INTO url_tag (url_id, tag) SELECT inserted.id, unnest($26::VARCHAR[]) FROM inserted;`
+	values := append(insertValues(url), textArray(url.Tags))
+
+	if len(limits) == 0 {
+		_, err := r.database.ExecContext(ctx, query, values...)
+		return err
+	}
+
+	tx, err := r.database.BeginTx(ctx, nil)
 	if err != nil {
-		return urls, err
+		return err
 	}
 
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO url (id, short, original, user_id) VALUES ($1, $2, $3, $4)
-		ON CONFLICT(original) DO UPDATE SET original=$3 
-		RETURNING id, short, original, user_id;
-	`)
+	remaining, err := lockLimits(ctx, tx, limits)
+	if err != nil {
+		return err
+	}
+
+	if remaining == 0 {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM url WHERE id=$1 OR original=$2)`, url.ID, url.Original).Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return ErrorURLDuplicate
+		}
+
+		return ErrorQuotaExceeded
+	}
+
+	if _, err = tx.ExecContext(ctx, query, values...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockLimits takes transaction advisory locks of limits' users in sorted order, so inserts of users sharing a limit
+// are serialized, and returns count of urls which still fit limits, negative value means unlimited.
+func lockLimits(ctx context.Context, tx *sql.Tx, limits []Limit) (int, error) {
+	for _, user := range limitUsers(limits) {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "quota:"+user); err != nil {
+			return 0, err
+		}
+	}
+
+	return remainingOf(limits, func(userIDs []string) (int, error) {
+		var count int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM url WHERE user_id=ANY($1::VARCHAR[]) AND is_deleted=false`, textArray(userIDs)).Scan(&count)
+		return count, err
+	})
+}
+
+// InsertMany adds many rows in url database table in one transaction with multi-row statements. Rows with already
+// stored original are replaced by stored ones, rows which don't fit limits aren't inserted and returned as zero URL.
+func (r PostgresRepository) InsertMany(ctx context.Context, urls []URL, limits ...Limit) ([]URL, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
 	if err != nil {
 		return urls, err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	for index, url := range urls {
-		var dbURL URL
+	accepted := urls
+	var fits []bool
+
+	if len(limits) > 0 {
+		remaining, err := lockLimits(ctx, tx, limits)
+		if err != nil {
+			return urls, err
+		}
 
-		err := stmt.QueryRowContext(ctx, url.ID, url.Short, url.Original, url.UserID).Scan(&dbURL.ID, &dbURL.Short, &dbURL.Original, &dbURL.UserID)
+		stored, err := storedOriginals(ctx, tx, urls)
 		if err != nil {
 			return urls, err
 		}
 
-		urls[index] = dbURL
+		fits = fitLimits(urls, func(original string) bool { return stored[original] }, remaining)
+		accepted = make([]URL, 0, len(urls))
+		for index, url := range urls {
+			if fits[index] {
+				accepted = append(accepted, url)
+			}
+		}
+	}
+
+	for start := 0; start < len(accepted); start += insertChunkSize {
+		end := start + insertChunkSize
+		if end > len(accepted) {
+			end = len(accepted)
+		}
+
+		if err := insertChunk(ctx, tx, accepted[start:end]); err != nil {
+			return urls, err
+		}
+	}
+
+	if fits != nil {
+		next := 0
+		for index := range urls {
+			if fits[index] {
+				urls[index] = accepted[next]
+				next++
+			} else {
+				urls[index] = URL{}
+			}
+		}
 	}
 
 	return urls, tx.Commit()
 }
 
+// storedOriginals returns set of originals of urls which are already stored in url table.
+func storedOriginals(ctx context.Context, tx *sql.Tx, urls []URL) (map[string]bool, error) {
+	originals := make([]string, len(urls))
+	for index, url := range urls {
+		originals[index] = url.Original
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT original FROM url WHERE original=ANY($1::VARCHAR[])`, textArray(originals))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stored := make(map[string]bool)
+	for rows.Next() {
+		var original string
+		if err := rows.Scan(&original); err != nil {
+			return nil, err
+		}
+
+		stored[original] = true
+	}
+
+	return stored, rows.Err()
+}
+
+// insertChunk inserts urls by one statement and replaces them by stored rows.
+func insertChunk(ctx context.Context, tx *sql.Tx, urls []URL) error {
+	first := make(map[string]int, len(urls))
+	rows := make([]string, 0, len(urls))
+	values := make([]interface{}, 0, len(urls)*25)
+
+	for index, url := range urls {
+		if _, ok := first[url.Original]; ok {
+			continue
+		}
+
+		first[url.Original] = index
+		rows = append(rows, insertPlaceholders(len(values)))
+		values = append(values, insertValues(url)...)
+	}
+
+	result, err := tx.QueryContext(ctx, `
+		INSERT INTO url (`+insertColumns+`)
+		VALUES `+strings.Join(rows, ", ")+`
+		ON CONFLICT(original) DO UPDATE SET original=EXCLUDED.original
+		RETURNING `+urlColumns+`;`, values...)
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]URL, len(rows))
+	for result.Next() {
+		dbURL, err := scanURL(result)
+		if err != nil {
+			result.Close()
+			return err
+		}
+
+		stored[dbURL.Original] = dbURL
+	}
+
+	result.Close()
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	var tagIDs, tags []string
+	for original, index := range first {
+		url, dbURL := urls[index], stored[original]
+		if dbURL.ID != url.ID || len(url.Tags) == 0 {
+			continue
+		}
+
+		for _, tag := range url.Tags {
+			tagIDs = append(tagIDs, url.ID)
+			tags = append(tags, tag)
+		}
+
+		dbURL.Tags = url.Tags
+		stored[original] = dbURL
+	}
+
+	if len(tags) > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO url_tag (url_id, tag) SELECT unnest($1::VARCHAR[]), unnest($2::VARCHAR[])`, textArray(tagIDs), textArray(tags))
+		if err != nil {
+			return err
+		}
+	}
+
+	for index, url := range urls {
+		urls[index] = stored[url.Original]
+	}
+
+	return nil
+}
+
 // Get select row by id from url table.
 func (r PostgresRepository) Get(ctx context.Context, id string) (URL, bool) {
-	var url URL
-
-	err := r.database.QueryRowContext(ctx, `SELECT id, short, original, user_id, is_deleted FROM url WHERE id=$1`, id).Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted)
+	url, err := scanURL(r.database.QueryRowContext(ctx, `SELECT `+urlColumns+` FROM url WHERE id=$1`, id))
 	if err != nil {
 		fmt.Println(err)
 		return url, false
@@ -125,51 +538,808 @@ func (r PostgresRepository) Get(ctx context.Context, id string) (URL, bool) {
 	return url, true
 }
 
-// GetAllByUser select many rows by user_id from url table.
-func (r PostgresRepository) GetAllByUser(ctx context.Context, userID string) ([]URL, error) {
-	var count int
-	e := r.database.QueryRowContext(ctx, `SELECT COUNT(*) FROM url WHERE user_id=$1 AND is_deleted=false`, userID).Scan(&count)
-	if e != nil {
-		return nil, e
+// GetAllByUser select page of user's not deleted rows from url table ordered and filtered by options.
+// Returns cursor of next page or empty string if page is last.
+func (r PostgresRepository) GetAllByUser(ctx context.Context, userID string, options ListOptions) ([]URL, string, error) {
+	column := "created_at"
+	if options.SortBy == SortByClicks {
+		column = "clicks"
 	}
 
-	rows, err := r.database.QueryContext(ctx, `SELECT id, short, original, user_id, is_deleted FROM url WHERE user_id=$1 AND is_deleted=false`, userID)
-	if err != nil {
-		return nil, err
+	direction, operator := "ASC", ">"
+	if options.Descending {
+		direction, operator = "DESC", "<"
+	}
+
+	conditions := []string{"user_id=$1", "is_deleted=false"}
+	args := []interface{}{userID}
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(options.Domain) > 0 {
+		pattern := `(^|\.)` + regexp.QuoteMeta(strings.ToLower(options.Domain)) + `$`
+		conditions = append(conditions, `lower(substring(original from '^[^:]+://(?:[^@/]*@)?([^/:?#]+)')) ~ `+arg(pattern))
+	}
+
+	if options.CreatedAfter != nil {
+		conditions = append(conditions, "created_at>"+arg(*options.CreatedAfter))
+	}
+
+	if options.CreatedBefore != nil {
+		conditions = append(conditions, "created_at<"+arg(*options.CreatedBefore))
+	}
+
+	if len(options.Search) > 0 {
+		conditions = append(conditions, "original ILIKE "+arg("%"+escapeLike(options.Search)+"%"))
+	}
+
+	if len(options.Tag) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM url_tag WHERE url_tag.url_id=url.id AND url_tag.tag="+arg(options.Tag)+")")
+	}
+
+	if options.Broken {
+		conditions = append(conditions, "(health->>'broken')::boolean")
+	}
+
+	if options.FolderID != nil {
+		if *options.FolderID == 0 {
+			conditions = append(conditions, "folder_id IS NULL")
+		} else {
+			conditions = append(conditions, "folder_id="+arg(*options.FolderID))
+		}
+	}
+
+	if len(options.Cursor) > 0 {
+		after, err := decodeCursor(options.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var value interface{} = time.Unix(0, after.Value)
+		if options.SortBy == SortByClicks {
+			value = after.Value
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", column, operator, arg(value), arg(after.ID)))
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM url WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+
+	if options.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", options.Limit+1)
 	}
-	err = rows.Err()
+
+	rows, err := r.database.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	defer rows.Close()
 
-	urls := make([]URL, count)
-
-	i := 0
+	urls := make([]URL, 0)
 
 	for rows.Next() {
-		var url URL
-		err := rows.Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted)
+		url, err := scanURL(rows)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
-		urls[i] = url
-		i++
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if options.Limit > 0 && len(urls) > options.Limit {
+		urls = urls[:options.Limit]
+		return urls, encodeCursor(urls[len(urls)-1], options.SortBy), nil
+	}
+
+	return urls, "", nil
+}
+
+// IncrementClicks increases clicks counter of row by id in url table, row which reached max_clicks returns ErrorURLExhausted.
+// Condition is checked by update itself, so concurrent clicks can't exceed the limit.
+func (r PostgresRepository) IncrementClicks(ctx context.Context, id string) error {
+	result, err := r.database.ExecContext(ctx, `UPDATE url SET clicks=clicks+1 WHERE id=$1 AND (max_clicks=0 OR clicks<max_clicks)`, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err == nil && count == 0 {
+		return ErrorURLExhausted
 	}
 
-	return urls[:i], nil
+	return err
 }
 
 // DeleteManyByUser delete many rows by user_id in url table.
 func (r PostgresRepository) DeleteManyByUser(ctx context.Context, urlIDs []string, userID string) bool {
-	param := "{" + strings.Join(urlIDs, ",") + "}"
-	_, err := r.database.ExecContext(ctx, `UPDATE url SET is_deleted=true WHERE user_id=$1 AND id=ANY($2::VARCHAR[])`, userID, param)
+	param := textArray(urlIDs)
+	_, err := r.database.ExecContext(ctx, `UPDATE url SET is_deleted=true, deleted_at=NOW(), updated_at=NOW() WHERE user_id=$1 AND id=ANY($2::VARCHAR[]) AND is_deleted=false`, userID, param)
 
 	return err == nil
 }
 
+// GetByOriginal select row by original url from url table.
+func (r PostgresRepository) GetByOriginal(ctx context.Context, original string) (URL, bool) {
+	url, err := scanURL(r.database.QueryRowContext(ctx, `SELECT `+urlColumns+` FROM url WHERE original=$1`, original))
+	if err != nil {
+		return url, false
+	}
+
+	return url, true
+}
+
+// SetDisabled disables or re-enables row by id in url table.
+func (r PostgresRepository) SetDisabled(ctx context.Context, id string, disabled bool, reason string, status int) (URL, error) {
+	url, err := scanURL(r.database.QueryRowContext(ctx, `
+		UPDATE url SET is_disabled=$2, disabled_reason=$3, disabled_status=$4, updated_at=NOW() WHERE id=$1
+		RETURNING `+urlColumns, id, disabled, reason, status))
+	if errors.Is(err, sql.ErrNoRows) {
+		return url, ErrorURLNotFound
+	}
+
+	return url, err
+}
+
+// DeleteAllByUser marks all user's rows in url table as deleted.
+func (r PostgresRepository) DeleteAllByUser(ctx context.Context, userID string) (int, error) {
+	result, err := r.database.ExecContext(ctx, `UPDATE url SET is_deleted=true, deleted_at=NOW(), updated_at=NOW() WHERE user_id=$1 AND is_deleted=false`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// CountActiveByUsers returns count of not deleted rows owned by any of users in url table.
+func (r PostgresRepository) CountActiveByUsers(ctx context.Context, userIDs []string) (int, error) {
+	var count int
+
+	param := textArray(userIDs)
+	err := r.database.QueryRowContext(ctx, `SELECT COUNT(*) FROM url WHERE user_id=ANY($1::VARCHAR[]) AND is_deleted=false`, param).Scan(&count)
+
+	return count, err
+}
+
+// InsertAuditRecord adds row in audit_log table.
+func (r PostgresRepository) InsertAuditRecord(ctx context.Context, record AuditRecord) error {
+	_, err := r.database.ExecContext(ctx, `INSERT INTO audit_log (actor, action, target, details) VALUES ($1, $2, $3, $4);`, record.Actor, record.Action, record.Target, record.Details)
+	return err
+}
+
+// GetAuditRecords select last rows from audit_log table, newest first.
+func (r PostgresRepository) GetAuditRecords(ctx context.Context, limit int) ([]AuditRecord, error) {
+	rows, err := r.database.QueryContext(ctx, `SELECT id, actor, action, target, details, created_at FROM audit_log ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var records []AuditRecord
+
+	for rows.Next() {
+		var record AuditRecord
+		err := rows.Scan(&record.ID, &record.Actor, &record.Action, &record.Target, &record.Details, &record.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// Update changes destination, redirect type and expiration of user's row in url table if it still has expected version.
+// Zero version skips version check. Previous state is saved to url_history table.
+func (r PostgresRepository) Update(ctx context.Context, url URL, version int) (URL, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return url, err
+	}
+
+	defer tx.Rollback()
+
+	current, err := scanURL(tx.QueryRowContext(ctx, `SELECT `+urlColumns+` FROM url WHERE id=$1 FOR UPDATE`, url.ID))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && current.IsDeleted) {
+		return url, ErrorURLNotFound
+	}
+	if err != nil {
+		return url, err
+	}
+
+	if current.UserID != url.UserID {
+		return current, ErrorURLForbidden
+	}
+
+	if version != 0 && current.Version != version {
+		return current, ErrorURLVersionMismatch
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO url_history (url_id, version, original, redirect_type, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		current.ID, current.Version, current.Original, current.RedirectType, current.ExpiresAt)
+	if err != nil {
+		return current, err
+	}
+
+	if !sameVariants(current.Variants, url.Variants) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM url_variant_stat WHERE url_id=$1`, url.ID); err != nil {
+			return current, err
+		}
+	}
+
+	updated, err := scanURL(tx.QueryRowContext(ctx, `
+		UPDATE url SET original=$2, redirect_type=$3, expires_at=$4, title=$5, description=$6, password_hash=$7,
+			not_before=$8, not_after=$9, fallback_url=$10, rules=$11, variants=$12,
+			geo_rules=$13, geo_default=$14, query_passthrough=$15, query_conflict=$16, utm=$17,
+			health=CASE WHEN original=$2 THEN health END, health_checked_at=CASE WHEN original=$2 THEN health_checked_at END,
+			page=CASE WHEN original=$2 THEN page END,
+			updated_at=NOW(), version=version+1 WHERE id=$1
+		RETURNING `+urlColumns, url.ID, url.Original, url.RedirectType, url.ExpiresAt, url.Title, url.Description, url.PasswordHash,
+		url.NotBefore, url.NotAfter, url.FallbackURL, jsonArray(url.Rules), jsonArray(url.Variants), jsonArray(url.GeoRules), url.GeoDefault, url.QueryPassthrough, url.QueryConflict, jsonObject(url.UTM)))
+	if err != nil {
+		if strings.Contains(err.Error(), pgerrcode.UniqueViolation) {
+			return current, ErrorURLDuplicate
+		}
+		return current, err
+	}
+
+	return updated, tx.Commit()
+}
+
+// GetHistory returns all url versions from oldest to current.
+func (r PostgresRepository) GetHistory(ctx context.Context, id string) ([]URLRevision, error) {
+	rows, err := r.database.QueryContext(ctx, `
+		SELECT version, original, redirect_type, expires_at, replaced_at FROM url_history WHERE url_id=$1
+		UNION ALL
+		SELECT version, original, redirect_type, expires_at, NULL FROM url WHERE id=$1
+		ORDER BY version`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []URLRevision
+
+	for rows.Next() {
+		var revision URLRevision
+		err := rows.Scan(&revision.Version, &revision.Original, &revision.RedirectType, &revision.ExpiresAt, &revision.ReplacedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, ErrorURLNotFound
+	}
+
+	return revisions, nil
+}
+
+// GetDeletedByUser select user's rows deleted since given time, newest deleted first.
+func (r PostgresRepository) GetDeletedByUser(ctx context.Context, userID string, since time.Time) ([]URL, error) {
+	rows, err := r.database.QueryContext(ctx, `SELECT `+urlColumns+` FROM url WHERE user_id=$1 AND is_deleted=true AND deleted_at>=$2 ORDER BY deleted_at DESC`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var urls []URL
+
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// RestoreManyByUser reverts deletion of user's rows deleted since given time and returns ids of restored rows.
+func (r PostgresRepository) RestoreManyByUser(ctx context.Context, urlIDs []string, userID string, since time.Time) ([]string, error) {
+	param := textArray(urlIDs)
+	rows, err := r.database.QueryContext(ctx, `
+		UPDATE url SET is_deleted=false, deleted_at=NULL, updated_at=NOW()
+		WHERE user_id=$1 AND id=ANY($2::VARCHAR[]) AND is_deleted=true AND deleted_at>=$3
+		RETURNING id`, userID, param, since)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var restored []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		restored = append(restored, id)
+	}
+
+	return restored, rows.Err()
+}
+
+// PurgeDeleted removes rows deleted before given time from url, url_history and url_tag tables.
+func (r PostgresRepository) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM url_history WHERE url_id IN (SELECT id FROM url WHERE is_deleted=true AND deleted_at<$1)`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM url_tag WHERE url_id IN (SELECT id FROM url WHERE is_deleted=true AND deleted_at<$1)`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM url_variant_stat WHERE url_id IN (SELECT id FROM url WHERE is_deleted=true AND deleted_at<$1)`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM url_country_stat WHERE url_id IN (SELECT id FROM url WHERE is_deleted=true AND deleted_at<$1)`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM url WHERE is_deleted=true AND deleted_at<$1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), tx.Commit()
+}
+
+// variantEventColumns maps variant events to columns of url_variant_stat table.
+var variantEventColumns = map[VariantEvent]string{
+	VariantClick:      "clicks",
+	VariantConversion: "conversions",
+}
+
+// RecordVariantEvent increases counter of event of url variant in url_variant_stat table.
+func (r PostgresRepository) RecordVariantEvent(ctx context.Context, id string, variant int, event VariantEvent) error {
+	column, ok := variantEventColumns[event]
+	if !ok {
+		return fmt.Errorf("unknown variant event %q", event)
+	}
+
+	_, err := r.database.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO url_variant_stat (url_id, variant, %[1]s) VALUES ($1, $2, 1)
+		ON CONFLICT (url_id, variant) DO UPDATE SET %[1]s=url_variant_stat.%[1]s+1`, column), id, variant)
+
+	return err
+}
+
+// GetVariantStats select counters of url variants from url_variant_stat table ordered by variant index.
+func (r PostgresRepository) GetVariantStats(ctx context.Context, id string) ([]VariantStats, error) {
+	rows, err := r.database.QueryContext(ctx, `SELECT variant, clicks, conversions FROM url_variant_stat WHERE url_id=$1 ORDER BY variant`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stats := make([]VariantStats, 0)
+
+	for rows.Next() {
+		var value VariantStats
+		if err := rows.Scan(&value.Variant, &value.Clicks, &value.Conversions); err != nil {
+			return nil, err
+		}
+
+		stats = append(stats, value)
+	}
+
+	return stats, rows.Err()
+}
+
+// RecordCountryClick increases clicks counter of url from country in url_country_stat table.
+func (r PostgresRepository) RecordCountryClick(ctx context.Context, id string, country string) error {
+	_, err := r.database.ExecContext(ctx, `
+		INSERT INTO url_country_stat (url_id, country, clicks) VALUES ($1, $2, 1)
+		ON CONFLICT (url_id, country) DO UPDATE SET clicks=url_country_stat.clicks+1`, id, country)
+
+	return err
+}
+
+// GetCountryStats select clicks of url by country from url_country_stat table ordered from most clicked.
+func (r PostgresRepository) GetCountryStats(ctx context.Context, id string) ([]CountryStats, error) {
+	rows, err := r.database.QueryContext(ctx, `SELECT country, clicks FROM url_country_stat WHERE url_id=$1 ORDER BY clicks DESC, country`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stats := make([]CountryStats, 0)
+
+	for rows.Next() {
+		var value CountryStats
+		if err := rows.Scan(&value.Country, &value.Clicks); err != nil {
+			return nil, err
+		}
+
+		stats = append(stats, value)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetDueHealthChecks select active rows of url table which were never checked or were checked before given time,
+// never checked and least recently checked first. Zero limit means all urls.
+func (r PostgresRepository) GetDueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]URL, error) {
+	rows, err := r.database.QueryContext(ctx, `
+		SELECT `+urlColumns+` FROM url WHERE is_deleted=false AND is_disabled=false AND (health_checked_at IS NULL OR health_checked_at<$1)
+		ORDER BY health_checked_at NULLS FIRST, id LIMIT NULLIF($2, 0)`, checkedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	urls := make([]URL, 0)
+
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// SetHealth saves result of destination check of row in url table, result is dropped if destination changed since check started.
+func (r PostgresRepository) SetHealth(ctx context.Context, id string, original string, health Health) error {
+	value, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.database.ExecContext(ctx, `UPDATE url SET health=$3, health_checked_at=$4 WHERE id=$1 AND original=$2`,
+		id, original, string(value), health.CheckedAt)
+
+	return err
+}
+
+// SetPage saves fetched metadata of destination page of row in url table, metadata is dropped if destination changed since fetch started.
+func (r PostgresRepository) SetPage(ctx context.Context, id string, original string, page Page) error {
+	value, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.database.ExecContext(ctx, `UPDATE url SET page=$3 WHERE id=$1 AND original=$2`, id, original, string(value))
+
+	return err
+}
+
+// ownURL locks user's not deleted row in url table within transaction.
+func ownURL(ctx context.Context, tx *sql.Tx, id string, userID string) (URL, error) {
+	url, err := scanURL(tx.QueryRowContext(ctx, `SELECT `+urlColumns+` FROM url WHERE id=$1 FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && url.IsDeleted) {
+		return url, ErrorURLNotFound
+	}
+	if err != nil {
+		return url, err
+	}
+
+	if url.UserID != userID {
+		return url, ErrorURLForbidden
+	}
+
+	return url, nil
+}
+
+// SetTags replaces rows of user's url in url_tag table.
+func (r PostgresRepository) SetTags(ctx context.Context, id string, userID string, tags []string) (URL, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return URL{}, err
+	}
+
+	defer tx.Rollback()
+
+	if url, err := ownURL(ctx, tx, id, userID); err != nil {
+		return url, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_tag WHERE url_id=$1`, id); err != nil {
+		return URL{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO url_tag (url_id, tag) SELECT $1, unnest($2::VARCHAR[])`, id, textArray(tags)); err != nil {
+		return URL{}, err
+	}
+
+	url, err := scanURL(tx.QueryRowContext(ctx, `UPDATE url SET updated_at=NOW(), tags_text=$2 WHERE id=$1 RETURNING `+urlColumns, id, strings.Join(tags, " ")))
+	if err != nil {
+		return url, err
+	}
+
+	return url, tx.Commit()
+}
+
+// GetTagsByUser select tags of user's not deleted rows with usage count from url_tag table.
+func (r PostgresRepository) GetTagsByUser(ctx context.Context, userID string) ([]TagUsage, error) {
+	rows, err := r.database.QueryContext(ctx, `
+		SELECT url_tag.tag, COUNT(*) FROM url_tag JOIN url ON url.id=url_tag.url_id
+		WHERE url.user_id=$1 AND url.is_deleted=false
+		GROUP BY url_tag.tag ORDER BY url_tag.tag`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := make([]TagUsage, 0)
+
+	for rows.Next() {
+		var tag TagUsage
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// RenameTag replaces tag on all user's rows in url_tag table and returns count of affected urls.
+func (r PostgresRepository) RenameTag(ctx context.Context, userID string, from string, to string) (int, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO url_tag (url_id, tag)
+		SELECT url_id, $3 FROM url_tag WHERE tag=$2 AND url_id IN (SELECT id FROM url WHERE user_id=$1)
+		ON CONFLICT DO NOTHING`, userID, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := r.deleteTag(ctx, tx, userID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// DeleteTag removes tag from all user's rows in url_tag table and returns count of affected urls.
+func (r PostgresRepository) DeleteTag(ctx context.Context, userID string, tag string) (int, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	count, err := r.deleteTag(ctx, tx, userID, tag)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+func (r PostgresRepository) deleteTag(ctx context.Context, tx *sql.Tx, userID string, tag string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `DELETE FROM url_tag WHERE tag=$2 AND url_id IN (SELECT id FROM url WHERE user_id=$1) RETURNING url_id`, userID, tag)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE url SET updated_at=NOW(), tags_text=`+tagsText+` WHERE id=ANY($1::VARCHAR[])`, textArray(ids))
+
+	return len(ids), err
+}
+
+// scanFolder scans folder table row selected as id, user_id, name, parent_id, created_at.
+func scanFolder(row rowScanner) (Folder, error) {
+	var (
+		folder   Folder
+		parentID sql.NullInt64
+	)
+
+	err := row.Scan(&folder.ID, &folder.UserID, &folder.Name, &parentID, &folder.CreatedAt)
+	folder.ParentID = parentID.Int64
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, ErrorFolderNotFound
+	}
+
+	return folder, err
+}
+
+// InsertFolder adds row in folder table.
+func (r PostgresRepository) InsertFolder(ctx context.Context, folder Folder) (Folder, error) {
+	return scanFolder(r.database.QueryRowContext(ctx, `
+		INSERT INTO folder (user_id, name, parent_id) VALUES ($1, $2, $3)
+		RETURNING id, user_id, name, parent_id, created_at`, folder.UserID, folder.Name, nullableID(folder.ParentID)))
+}
+
+// GetFolder select user's row by id from folder table.
+func (r PostgresRepository) GetFolder(ctx context.Context, id int64, userID string) (Folder, error) {
+	return scanFolder(r.database.QueryRowContext(ctx, `SELECT id, user_id, name, parent_id, created_at FROM folder WHERE id=$1 AND user_id=$2`, id, userID))
+}
+
+// GetFoldersByUser select all user's rows from folder table ordered by id.
+func (r PostgresRepository) GetFoldersByUser(ctx context.Context, userID string) ([]Folder, error) {
+	rows, err := r.database.QueryContext(ctx, `SELECT id, user_id, name, parent_id, created_at FROM folder WHERE user_id=$1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	folders := make([]Folder, 0)
+
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// UpdateFolder changes name and parent of user's row in folder table.
+func (r PostgresRepository) UpdateFolder(ctx context.Context, folder Folder) (Folder, error) {
+	return scanFolder(r.database.QueryRowContext(ctx, `
+		UPDATE folder SET name=$3, parent_id=$4 WHERE id=$1 AND user_id=$2
+		RETURNING id, user_id, name, parent_id, created_at`, folder.ID, folder.UserID, folder.Name, nullableID(folder.ParentID)))
+}
+
+// DeleteFolder removes user's row from folder table, its subfolders and urls are moved to parent folder.
+func (r PostgresRepository) DeleteFolder(ctx context.Context, id int64, userID string) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	folder, err := scanFolder(tx.QueryRowContext(ctx, `SELECT id, user_id, name, parent_id, created_at FROM folder WHERE id=$1 AND user_id=$2 FOR UPDATE`, id, userID))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE folder SET parent_id=$2 WHERE parent_id=$1`, id, nullableID(folder.ParentID)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE url SET folder_id=$2 WHERE folder_id=$1`, id, nullableID(folder.ParentID)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM folder WHERE id=$1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetFolder moves user's row in url table to folder, zero folderID moves it out of any folder.
+func (r PostgresRepository) SetFolder(ctx context.Context, id string, userID string, folderID int64) (URL, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return URL{}, err
+	}
+
+	defer tx.Rollback()
+
+	if url, err := ownURL(ctx, tx, id, userID); err != nil {
+		return url, err
+	}
+
+	url, err := scanURL(tx.QueryRowContext(ctx, `UPDATE url SET folder_id=$2, updated_at=NOW() WHERE id=$1 RETURNING `+urlColumns, id, nullableID(folderID)))
+	if err != nil {
+		return url, err
+	}
+
+	return url, tx.Commit()
+}
+
+// Search select user's not deleted rows matching query by search_vector column ordered by rank,
+// zero limit means unlimited.
+func (r PostgresRepository) Search(ctx context.Context, userID string, query string, limit int) ([]SearchHit, error) {
+	hits := make([]SearchHit, 0)
+
+	tsquery := tsQuery(query)
+	if len(tsquery) == 0 {
+		return hits, nil
+	}
+
+	rows, err := r.database.QueryContext(ctx, `
+		SELECT `+urlColumns+`, ts_rank(search_vector, query) AS rank
+		FROM url, to_tsquery('simple', $2) AS query
+		WHERE user_id=$1 AND is_deleted=false AND search_vector @@ query
+		ORDER BY rank DESC, id
+		LIMIT NULLIF($3, 0)`, userID, tsquery, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+
+		hit.URL, err = scanURL(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &hit.Rank)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
 // Close close database connection.
 func (r PostgresRepository) Close() error {
 	return r.database.Close()
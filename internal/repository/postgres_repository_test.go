@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertPlaceholders(t *testing.T) {
+	first := insertPlaceholders(0)
+	assert.Equal(t, "($1, $2, $3, $4, $5, $6, $7, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)", first)
+	assert.Equal(t, len(insertValues(URL{})), 25)
+
+	second := insertPlaceholders(25)
+	assert.Contains(t, second, "($26, $27,")
+	assert.Contains(t, second, "$32, $32, $33")
+	assert.Contains(t, second, "$50)")
+}
+
+func TestTextArray(t *testing.T) {
+	assert.Equal(t, "{}", textArray(nil))
+	assert.Equal(t, `{"abc123","a,b","x\"}","back\\slash"}`, textArray([]string{"abc123", "a,b", `x"}`, `back\slash`}))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/LorezV/url-shorter.git/internal/config"
 	"github.com/LorezV/url-shorter.git/internal/utils"
+	"time"
 )
 
 // GlobalRepository it's repository variable.
@@ -13,21 +14,101 @@ var GlobalRepository Repository
 
 // Repository is a interface with contains methods for CRUD operations with data.
 type Repository interface {
-	Insert(ctx context.Context, url URL) (URL, error)
-	InsertMany(ctx context.Context, urls []URL) ([]URL, error)
+	Insert(ctx context.Context, url URL, limits ...Limit) (URL, error)
+	InsertMany(ctx context.Context, urls []URL, limits ...Limit) ([]URL, error)
 	Get(ctx context.Context, id string) (URL, bool)
-	GetAllByUser(ctx context.Context, userID string) ([]URL, error)
+	GetAllByUser(ctx context.Context, userID string, options ListOptions) ([]URL, string, error)
 	DeleteManyByUser(ctx context.Context, urlIDs []string, userID string) bool
+	GetByOriginal(ctx context.Context, original string) (URL, bool)
+	SetDisabled(ctx context.Context, id string, disabled bool, reason string, status int) (URL, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int, error)
+	CountActiveByUsers(ctx context.Context, userIDs []string) (int, error)
+	InsertAuditRecord(ctx context.Context, record AuditRecord) error
+	GetAuditRecords(ctx context.Context, limit int) ([]AuditRecord, error)
+	Update(ctx context.Context, url URL, version int) (URL, error)
+	GetHistory(ctx context.Context, id string) ([]URLRevision, error)
+	GetDeletedByUser(ctx context.Context, userID string, since time.Time) ([]URL, error)
+	RestoreManyByUser(ctx context.Context, urlIDs []string, userID string, since time.Time) ([]string, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+	IncrementClicks(ctx context.Context, id string) error
+	RecordVariantEvent(ctx context.Context, id string, variant int, event VariantEvent) error
+	GetVariantStats(ctx context.Context, id string) ([]VariantStats, error)
+	RecordCountryClick(ctx context.Context, id string, country string) error
+	GetCountryStats(ctx context.Context, id string) ([]CountryStats, error)
+	GetDueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]URL, error)
+	SetHealth(ctx context.Context, id string, original string, health Health) error
+	SetPage(ctx context.Context, id string, original string, page Page) error
+	SetTags(ctx context.Context, id string, userID string, tags []string) (URL, error)
+	GetTagsByUser(ctx context.Context, userID string) ([]TagUsage, error)
+	RenameTag(ctx context.Context, userID string, from string, to string) (int, error)
+	DeleteTag(ctx context.Context, userID string, tag string) (int, error)
+	InsertFolder(ctx context.Context, folder Folder) (Folder, error)
+	GetFolder(ctx context.Context, id int64, userID string) (Folder, error)
+	GetFoldersByUser(ctx context.Context, userID string) ([]Folder, error)
+	UpdateFolder(ctx context.Context, folder Folder) (Folder, error)
+	DeleteFolder(ctx context.Context, id int64, userID string) error
+	SetFolder(ctx context.Context, id string, userID string, folderID int64) (URL, error)
+	// Search returns user's urls matching query ordered by rank, zero limit means unlimited.
+	Search(ctx context.Context, userID string, query string, limit int) ([]SearchHit, error)
 	Close() error
 }
 
 // URL entity represent database table url
 type URL struct {
-	ID        string `json:"id"`
-	Original  string `json:"original_url"`
-	Short     string `json:"short_url"`
-	UserID    string `json:"user_id"`
-	IsDeleted bool   `json:"-"`
+	ID               string            `json:"id"`
+	Original         string            `json:"original_url"`
+	Short            string            `json:"short_url"`
+	UserID           string            `json:"user_id"`
+	IsDeleted        bool              `json:"-"`
+	IsDisabled       bool              `json:"is_disabled,omitempty"`
+	DisabledReason   string            `json:"disabled_reason,omitempty"`
+	DisabledStatus   int               `json:"disabled_status,omitempty"`
+	RedirectType     int               `json:"redirect_type,omitempty"`
+	ExpiresAt        *time.Time        `json:"expires_at,omitempty"`
+	Version          int               `json:"version,omitempty"`
+	DeletedAt        *time.Time        `json:"deleted_at,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Clicks           int64             `json:"clicks,omitempty"`
+	MaxClicks        int64             `json:"max_clicks,omitempty"`
+	NotBefore        *time.Time        `json:"not_before,omitempty"`
+	NotAfter         *time.Time        `json:"not_after,omitempty"`
+	FallbackURL      string            `json:"fallback_url,omitempty"`
+	Rules            []RoutingRule     `json:"rules,omitempty"`
+	Variants         []Variant         `json:"variants,omitempty"`
+	GeoRules         []GeoRule         `json:"geo_rules,omitempty"`
+	GeoDefault       string            `json:"geo_default,omitempty"`
+	QueryPassthrough *bool             `json:"query_passthrough,omitempty"`
+	QueryConflict    string            `json:"query_conflict,omitempty"`
+	UTM              map[string]string `json:"utm,omitempty"`
+	Health           *Health           `json:"health,omitempty"`
+	Page             *Page             `json:"page,omitempty"`
+	CreatorIP        string            `json:"creator_ip,omitempty"`
+	CreatorUserAgent string            `json:"creator_user_agent,omitempty"`
+	Title            string            `json:"title,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	FolderID         int64             `json:"folder_id,omitempty"`
+	PasswordHash     string            `json:"password_hash,omitempty"`
+}
+
+// URLRevision entity represent database table url_history, it keeps url destination of previous versions.
+type URLRevision struct {
+	Version      int        `json:"version"`
+	Original     string     `json:"original_url"`
+	RedirectType int        `json:"redirect_type,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	ReplacedAt   *time.Time `json:"replaced_at,omitempty"`
+}
+
+// AuditRecord entity represent database table audit_log, one row per admin action.
+type AuditRecord struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // MakeURL is a constructor from URL type.
@@ -42,9 +123,24 @@ func MakeURL(original string, userID string) (URL, error) {
 	url.ID = id
 	url.Short = fmt.Sprintf("%s/%s", config.AppConfig.BaseURL, id)
 	url.IsDeleted = false
+	url.Version = 1
+	url.CreatedAt = time.Now()
+	url.UpdatedAt = url.CreatedAt
 
 	return url, nil
 }
 
 // ErrorURLDuplicate is error which returning when url with id already exists in database.
 var ErrorURLDuplicate = errors.New("url already exists")
+
+// ErrorURLNotFound is error which returning when url with id doesn't exist in database.
+var ErrorURLNotFound = errors.New("url not found")
+
+// ErrorURLForbidden is error which returning when user changes url of another user.
+var ErrorURLForbidden = errors.New("url belongs to another user")
+
+// ErrorURLExhausted is error which returning when url reached its max clicks.
+var ErrorURLExhausted = errors.New("url reached max clicks")
+
+// ErrorURLVersionMismatch is error which returning when url was changed since version expected by update.
+var ErrorURLVersionMismatch = errors.New("url version mismatch")
@@ -0,0 +1,66 @@
+package repository
+
+import "github.com/LorezV/url-shorter.git/internal/useragent"
+
+// RoutingRule sends clients matching all its non-empty conditions to Destination instead of url original.
+type RoutingRule struct {
+	OS          string `json:"os,omitempty"`
+	Device      string `json:"device,omitempty"`
+	Bot         *bool  `json:"bot,omitempty"`
+	Destination string `json:"destination"`
+}
+
+// Matches reports whether client agent satisfies rule conditions.
+func (rule RoutingRule) Matches(agent useragent.Agent) bool {
+	if len(rule.OS) > 0 && rule.OS != agent.OS {
+		return false
+	}
+
+	if len(rule.Device) > 0 && rule.Device != agent.Device {
+		return false
+	}
+
+	return rule.Bot == nil || *rule.Bot == agent.Bot
+}
+
+// GeoRule sends clients from any of Countries (ISO 3166-1 alpha-2 codes) to Destination.
+type GeoRule struct {
+	Countries   []string `json:"countries"`
+	Destination string   `json:"destination"`
+}
+
+// CountryStats contains clicks of url from country, empty country means it is unknown.
+type CountryStats struct {
+	Country string `json:"country"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// RouteGeo returns destination of first url geo rule containing client country or url geo default, it reports false
+// when url has no geo routing or neither rule nor default applies.
+func (url URL) RouteGeo(country string) (string, bool) {
+	for _, rule := range url.GeoRules {
+		for _, value := range rule.Countries {
+			if value == country {
+				return rule.Destination, true
+			}
+		}
+	}
+
+	if len(url.GeoDefault) > 0 {
+		return url.GeoDefault, true
+	}
+
+	return url.Original, false
+}
+
+// Route returns destination of first url rule matching client agent, it reports false and returns url original
+// when no rule matches.
+func (url URL) Route(agent useragent.Agent) (string, bool) {
+	for _, rule := range url.Rules {
+		if rule.Matches(agent) {
+			return rule.Destination, true
+		}
+	}
+
+	return url.Original, false
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/LorezV/url-shorter.git/internal/search"
+	"strings"
+)
+
+// SearchHit is url found by full-text search with its rank, higher rank is better.
+type SearchHit struct {
+	URL  URL
+	Rank float64
+}
+
+// searchFields returns weighted url parts indexed for full-text search, url id is its alias.
+func searchFields(url URL) []search.Field {
+	return []search.Field{
+		{Text: url.ID, Weight: search.WeightAlias},
+		{Text: url.Title, Weight: search.WeightTitle},
+		{Text: strings.Join(url.Tags, " "), Weight: search.WeightTags},
+		{Text: url.Original, Weight: search.WeightOriginal},
+	}
+}
+
+// tsQuery converts search query to postgresql tsquery matching every token as prefix.
+func tsQuery(query string) string {
+	tokens := search.Tokenize(query)
+	for index, token := range tokens {
+		tokens[index] = token + ":*"
+	}
+
+	return strings.Join(tokens, " & ")
+}
@@ -0,0 +1,38 @@
+package repository
+
+// Variant is one of weighted destinations of url which splits traffic between them.
+type Variant struct {
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// VariantEvent is kind of event counted per url variant.
+type VariantEvent string
+
+// Events counted per url variant.
+const (
+	VariantClick      VariantEvent = "click"
+	VariantConversion VariantEvent = "conversion"
+)
+
+// VariantStats contains counters of url variant by its index.
+type VariantStats struct {
+	Variant     int   `json:"variant"`
+	Clicks      int64 `json:"clicks"`
+	Conversions int64 `json:"conversions"`
+}
+
+// sameVariants reports whether variants lists are equal, counters of url are reset when its variants change.
+func sameVariants(a, b []Variant) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,273 @@
+package screening
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+// GlobalBlocklist it's blocklist variable which is checked on url creation and redirect.
+var GlobalBlocklist = MakeBlocklist("")
+
+// Entry types of blocklist.
+const (
+	TypeDomain = "domain"
+	TypeRegex  = "regex"
+	TypeCIDR   = "cidr"
+)
+
+// ErrorBlocked is error which returning when destination url matches blocklist.
+var ErrorBlocked = errors.New("destination is blocked")
+
+// ErrorInvalidEntry is error which returning when blocklist entry can't be parsed.
+var ErrorInvalidEntry = errors.New("invalid blocklist entry")
+
+// Entry is a single blocklist rule. Domain blocks host and all its subdomains, regex is matched against whole url
+// and cidr blocks urls with ip literal host from network.
+type Entry struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type rule struct {
+	entry   Entry
+	pattern *regexp.Regexp
+	network *net.IPNet
+}
+
+// Blocklist contains rules loaded from file. File contains one "type:value" entry per line, lines starting with # are comments
+// and lines without type are domains.
+type Blocklist struct {
+	mutex sync.RWMutex
+	path  string
+	rules []rule
+}
+
+// MakeBlocklist is constructor for Blocklist, empty path means in-memory blocklist.
+func MakeBlocklist(path string) *Blocklist {
+	return &Blocklist{path: path}
+}
+
+// Load replaces blocklist rules with rules from file, missing file means empty blocklist.
+func (b *Blocklist) Load() error {
+	if len(b.path) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		b.mutex.Lock()
+		b.rules = nil
+		b.mutex.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var rules []rule
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		entry := Entry{Type: TypeDomain, Value: text}
+		if kind, value, ok := strings.Cut(text, ":"); ok && isType(kind) {
+			entry = Entry{Type: kind, Value: strings.TrimSpace(value)}
+		}
+
+		parsed, err := makeRule(entry)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", b.path, line, err)
+		}
+
+		rules = append(rules, parsed)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	b.rules = rules
+	b.mutex.Unlock()
+
+	return nil
+}
+
+// Check returns first entry which blocks url.
+func (b *Blocklist) Check(rawURL string) (Entry, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	ip := net.ParseIP(host)
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, r := range b.rules {
+		switch r.entry.Type {
+		case TypeDomain:
+			if host == r.entry.Value || strings.HasSuffix(host, "."+r.entry.Value) {
+				return r.entry, true
+			}
+		case TypeRegex:
+			if r.pattern.MatchString(rawURL) {
+				return r.entry, true
+			}
+		case TypeCIDR:
+			if ip != nil && r.network.Contains(ip) {
+				return r.entry, true
+			}
+		}
+	}
+
+	return Entry{}, false
+}
+
+// Entries returns copy of all blocklist entries.
+func (b *Blocklist) Entries() []Entry {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	entries := make([]Entry, len(b.rules))
+	for index, r := range b.rules {
+		entries[index] = r.entry
+	}
+
+	return entries
+}
+
+// Add validates entry, adds it to blocklist and saves blocklist file. Returns false if entry already exists.
+// Blocklist is changed only if file is saved.
+func (b *Blocklist) Add(entry Entry) (bool, error) {
+	parsed, err := makeRule(entry)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrorInvalidEntry, err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, r := range b.rules {
+		if r.entry == parsed.entry {
+			return false, nil
+		}
+	}
+
+	rules := make([]rule, 0, len(b.rules)+1)
+	rules = append(append(rules, b.rules...), parsed)
+
+	if err := b.save(rules); err != nil {
+		return false, err
+	}
+
+	b.rules = rules
+	return true, nil
+}
+
+// Remove removes entry from blocklist and saves blocklist file. Returns false if entry doesn't exist.
+// Blocklist is changed only if file is saved.
+func (b *Blocklist) Remove(entry Entry) (bool, error) {
+	parsed, err := makeRule(entry)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrorInvalidEntry, err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for index, r := range b.rules {
+		if r.entry == parsed.entry {
+			rules := make([]rule, 0, len(b.rules)-1)
+			rules = append(append(rules, b.rules[:index]...), b.rules[index+1:]...)
+
+			if err := b.save(rules); err != nil {
+				return false, err
+			}
+
+			b.rules = rules
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// save rewrites blocklist file with rules, comments of original file are not kept. Caller must hold mutex.
+func (b *Blocklist) save(rules []rule) error {
+	if len(b.path) == 0 {
+		return nil
+	}
+
+	var builder strings.Builder
+	for _, r := range rules {
+		builder.WriteString(r.entry.Type + ":" + r.entry.Value + "\n")
+	}
+
+	temp := b.path + ".tmp"
+	if err := os.WriteFile(temp, []byte(builder.String()), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, b.path)
+}
+
+func makeRule(entry Entry) (rule, error) {
+	entry.Value = strings.TrimSpace(entry.Value)
+	if len(entry.Value) == 0 {
+		return rule{}, errors.New("blocklist entry value is empty")
+	}
+
+	r := rule{entry: entry}
+
+	switch entry.Type {
+	case TypeDomain:
+		domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(entry.Value, "."))
+		if err != nil {
+			return rule{}, err
+		}
+		r.entry.Value = strings.ToLower(domain)
+	case TypeRegex:
+		pattern, err := regexp.Compile(entry.Value)
+		if err != nil {
+			return rule{}, err
+		}
+		r.pattern = pattern
+	case TypeCIDR:
+		_, network, err := net.ParseCIDR(entry.Value)
+		if err != nil {
+			return rule{}, err
+		}
+		r.network = network
+		r.entry.Value = network.String()
+	default:
+		return rule{}, fmt.Errorf("unknown blocklist entry type %q", entry.Type)
+	}
+
+	return r, nil
+}
+
+func isType(kind string) bool {
+	return kind == TypeDomain || kind == TypeRegex || kind == TypeCIDR
+}
@@ -0,0 +1,102 @@
+package screening
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklistCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte(`# phishing domains
+evil.example
+domain:пример.рф
+regex:^https?://[^/]*paypa1
+cidr:10.0.0.0/8
+`), 0644))
+
+	blocklist := MakeBlocklist(path)
+	require.NoError(t, blocklist.Load())
+
+	tests := []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{name: "Blocked domain.", url: "https://evil.example/login", blocked: true},
+		{name: "Subdomain of blocked domain.", url: "https://login.evil.example/", blocked: true},
+		{name: "Domain with same suffix.", url: "https://notevil.example/", blocked: false},
+		{name: "International domain in punycode.", url: "https://xn--e1afmkfd.xn--p1ai/", blocked: true},
+		{name: "Regex pattern.", url: "http://secure-paypa1.example/", blocked: true},
+		{name: "Ip literal from cidr.", url: "http://10.1.2.3:8080/", blocked: true},
+		{name: "Ip literal outside cidr.", url: "http://192.168.1.1/", blocked: false},
+		{name: "Clean url.", url: "https://practicum.yandex.ru/", blocked: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, blocked := blocklist.Check(tt.url)
+			assert.Equal(t, tt.blocked, blocked)
+		})
+	}
+}
+
+func TestBlocklistAddRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	blocklist := MakeBlocklist(path)
+	require.NoError(t, blocklist.Load())
+
+	added, err := blocklist.Add(Entry{Type: TypeDomain, Value: "Evil.Example"})
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = blocklist.Add(Entry{Type: TypeDomain, Value: "evil.example"})
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	_, err = blocklist.Add(Entry{Type: TypeCIDR, Value: "not a network"})
+	assert.Error(t, err)
+
+	reloaded := MakeBlocklist(path)
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, []Entry{{Type: TypeDomain, Value: "evil.example"}}, reloaded.Entries())
+
+	removed, err := blocklist.Remove(Entry{Type: TypeDomain, Value: "evil.example"})
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	require.NoError(t, reloaded.Load())
+	assert.Empty(t, reloaded.Entries())
+}
+
+func TestBlocklistSaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("evil.example\n"), 0644))
+
+	blocklist := MakeBlocklist(path)
+	require.NoError(t, blocklist.Load())
+
+	_, err := blocklist.Add(Entry{Type: TypeCIDR, Value: "not a network"})
+	assert.ErrorIs(t, err, ErrorInvalidEntry)
+
+	// Temporary file can't be written into directory replaced by a file.
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0644))
+	defer os.Remove(dir)
+
+	added, err := blocklist.Add(Entry{Type: TypeDomain, Value: "bad.example"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrorInvalidEntry)
+	assert.False(t, added)
+
+	removed, err := blocklist.Remove(Entry{Type: TypeDomain, Value: "evil.example"})
+	assert.Error(t, err)
+	assert.False(t, removed)
+
+	assert.Equal(t, []Entry{{Type: TypeDomain, Value: "evil.example"}}, blocklist.Entries())
+}
@@ -0,0 +1,169 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Field weights of url parts, they match default weights of postgresql ts_rank for A, B and C labels.
+const (
+	WeightAlias    = 1.0
+	WeightTitle    = 0.4
+	WeightTags     = 0.4
+	WeightOriginal = 0.2
+)
+
+// stopWords are tokens present in almost every url which don't help to find it.
+var stopWords = map[string]bool{
+	"http":  true,
+	"https": true,
+	"www":   true,
+}
+
+// Field is a part of indexed document with weight of its terms in score.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matched by query with its score, higher score is better.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Tokenize splits text to lowercase words of letters and digits without stop words.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := words[:0]
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens
+}
+
+// Index is in-process inverted index, it maps terms to documents containing them.
+type Index struct {
+	mutex    sync.RWMutex
+	postings map[string]map[string]float64
+	terms    map[string][]string
+}
+
+// MakeIndex is constructor for Index.
+func MakeIndex() *Index {
+	return &Index{postings: make(map[string]map[string]float64), terms: make(map[string][]string)}
+}
+
+// Put indexes document by id replacing its previous version.
+func (i *Index) Put(id string, fields ...Field) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.remove(id)
+
+	weights := make(map[string]float64)
+	for _, field := range fields {
+		for _, token := range Tokenize(field.Text) {
+			weights[token] += field.Weight
+		}
+	}
+
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[string]float64)
+		}
+
+		i.postings[term][id] = weight
+		terms = append(terms, term)
+	}
+
+	i.terms[id] = terms
+}
+
+// Remove deletes document by id from index.
+func (i *Index) Remove(id string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id string) {
+	for _, term := range i.terms[id] {
+		delete(i.postings[term], id)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+
+	delete(i.terms, id)
+}
+
+// Search returns documents containing every query token as a term prefix ordered by score.
+// Terms are scored by field weight and inverse document frequency.
+func (i *Index) Search(query string) []Hit {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	total := float64(len(i.terms))
+	var scores map[string]float64
+
+	for _, token := range tokens {
+		matched := make(map[string]float64)
+
+		for term, documents := range i.postings {
+			if !strings.HasPrefix(term, token) {
+				continue
+			}
+
+			idf := math.Log(1 + total/float64(len(documents)))
+			for id, weight := range documents {
+				if score := weight * idf; score > matched[id] {
+					matched[id] = score
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = matched
+			continue
+		}
+
+		for id, score := range scores {
+			if value, ok := matched[id]; ok {
+				scores[id] = score + value
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	return hits
+}
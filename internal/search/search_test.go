@@ -0,0 +1,62 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "Url is split by punctuation without scheme and www.",
+			text: "https://www.docs.example.com/Q3-Pricing?lang=en",
+			want: []string{"docs", "example", "com", "q3", "pricing", "lang", "en"},
+		},
+		{
+			name: "Unicode words are kept.",
+			text: "Цены на Q3",
+			want: []string{"цены", "на", "q3"},
+		},
+		{
+			name: "Only punctuation.",
+			text: "--//",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Tokenize(tt.text))
+		})
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := MakeIndex()
+	index.Put("pricing", Field{Text: "pricing", Weight: WeightAlias}, Field{Text: "https://docs.example.com/q3", Weight: WeightOriginal})
+	index.Put("deck", Field{Text: "Q3 pricing deck", Weight: WeightTitle}, Field{Text: "https://slides.example.com/x", Weight: WeightOriginal})
+	index.Put("blog", Field{Text: "https://blog.example.com/pricing-history", Weight: WeightOriginal})
+
+	hits := index.Search("pricing")
+	require.Len(t, hits, 3)
+	assert.Equal(t, "pricing", hits[0].ID)
+	assert.Equal(t, "blog", hits[2].ID)
+
+	hits = index.Search("q3 pric")
+	require.Len(t, hits, 2)
+	assert.Equal(t, "pricing", hits[0].ID)
+	assert.Equal(t, "deck", hits[1].ID)
+
+	index.Put("deck", Field{Text: "Annual report", Weight: WeightTitle})
+	assert.Len(t, index.Search("q3 pricing"), 1)
+
+	index.Remove("pricing")
+	assert.Empty(t, index.Search("q3 pricing"))
+	assert.Empty(t, index.Search("https://"))
+}
@@ -0,0 +1,329 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// Limits of fetched page and its metadata.
+const (
+	MaxRedirects         = 5
+	MaxTitleLength       = 300
+	MaxDescriptionLength = 1000
+)
+
+// GlobalFetcher fetches metadata of new links destinations, nil disables fetching.
+var GlobalFetcher *Fetcher
+
+// ErrorForbiddenAddress is error which returning when destination resolves to loopback, private or other not public address.
+var ErrorForbiddenAddress = errors.New("destination address is not public")
+
+// ErrorNotHTML is error which returning when destination isn't html page.
+var ErrorNotHTML = errors.New("destination is not html page")
+
+// reservedNetworks are not public ranges which net.IP methods don't recognize.
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+func mustParseCIDR(value string) *net.IPNet {
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// IsPublic reports whether ip is routable public address.
+func IsPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PublicOnly is net.Dialer control which allows connections only to public addresses. It's called with resolved
+// address, so names pointing to private addresses are rejected too.
+func PublicOnly(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !IsPublic(ip) {
+		return ErrorForbiddenAddress
+	}
+
+	return nil
+}
+
+// Metadata is title, description and favicon of html page.
+type Metadata struct {
+	Title       string
+	Description string
+	Favicon     string
+}
+
+// Fetcher downloads html pages with limits of size, time and concurrency. Connections are made only to public
+// addresses, the check is done after name resolution, so it covers redirects and names pointing to private addresses.
+type Fetcher struct {
+	Client   *http.Client
+	MaxBytes int64
+
+	slots chan struct{}
+	queue chan job
+}
+
+// job is queued fetch of destination, done receives its result.
+type job struct {
+	destination string
+	done        func(ctx context.Context, metadata Metadata, err error)
+}
+
+// MakeFetcher is constructor for Fetcher. Timeout limits whole fetch including redirects, maxBytes limits read
+// part of page, queueSize limits count of fetches waiting for worker. With allowPrivate connections to not public
+// addresses are allowed, it's meant for tests only.
+func MakeFetcher(timeout time.Duration, maxBytes int64, concurrency int, queueSize int, allowPrivate bool) *Fetcher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = PublicOnly
+	}
+
+	return &Fetcher{
+		Client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          concurrency,
+				IdleConnTimeout:       time.Minute,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > MaxRedirects {
+					return errors.New("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		MaxBytes: maxBytes,
+		slots:    make(chan struct{}, concurrency),
+		queue:    make(chan job, queueSize),
+	}
+}
+
+// Enqueue queues fetch of destination, done is called by worker with its result. Returns false and drops fetch
+// when queue is full.
+func (f *Fetcher) Enqueue(destination string, done func(ctx context.Context, metadata Metadata, err error)) bool {
+	select {
+	case f.queue <- job{destination: destination, done: done}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run fetches queued destinations by as many workers as fetcher's concurrency until context is done. Fetches in
+// progress are cancelled with context and Run returns when all workers are stopped.
+func (f *Fetcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < cap(f.slots); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-f.queue:
+					metadata, err := f.Fetch(ctx, job.destination)
+					job.done(ctx, metadata, err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Fetch downloads destination page and returns its metadata. Favicon is absolute url of icon declared by page
+// or /favicon.ico of its site.
+func (f *Fetcher) Fetch(ctx context.Context, destination string) (Metadata, error) {
+	select {
+	case <-ctx.Done():
+		return Metadata{}, ctx.Err()
+	case f.slots <- struct{}{}:
+	}
+	defer func() { <-f.slots }()
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return Metadata{}, fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "url-shorter-unfurl/1.0")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return Metadata{}, fmt.Errorf("destination responded with status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, ErrorNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.MaxBytes), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return parse(body, resp.Request.URL), nil
+}
+
+// parse reads metadata from head of html document, page is resolved against base.
+func parse(body io.Reader, base *url.URL) Metadata {
+	var (
+		metadata               Metadata
+		ogTitle, ogDescription string
+		icon                   string
+		inTitle                bool
+		title                  strings.Builder
+	)
+
+	tokenizer := html.NewTokenizer(body)
+
+loop:
+	for {
+		tokenType := tokenizer.Next()
+
+		switch tokenType {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			attributes := map[string]string{}
+			for hasAttributes {
+				var key, value []byte
+				key, value, hasAttributes = tokenizer.TagAttr()
+				attributes[string(key)] = string(value)
+			}
+
+			switch string(name) {
+			case "title":
+				inTitle = tokenType == html.StartTagToken && title.Len() == 0
+			case "body":
+				break loop
+			case "meta":
+				switch {
+				case strings.EqualFold(attributes["name"], "description"):
+					metadata.Description = attributes["content"]
+				case attributes["property"] == "og:title":
+					ogTitle = attributes["content"]
+				case attributes["property"] == "og:description":
+					ogDescription = attributes["content"]
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attributes["rel"])) {
+					if rel == "icon" && len(icon) == 0 && len(attributes["href"]) > 0 {
+						icon = attributes["href"]
+					}
+				}
+			}
+		}
+	}
+
+	metadata.Title = clean(title.String(), MaxTitleLength)
+	if len(metadata.Title) == 0 {
+		metadata.Title = clean(ogTitle, MaxTitleLength)
+	}
+
+	metadata.Description = clean(metadata.Description, MaxDescriptionLength)
+	if len(metadata.Description) == 0 {
+		metadata.Description = clean(ogDescription, MaxDescriptionLength)
+	}
+
+	if len(icon) == 0 {
+		icon = "/favicon.ico"
+	}
+	if favicon, err := base.Parse(icon); err == nil && (favicon.Scheme == "http" || favicon.Scheme == "https") {
+		metadata.Favicon = favicon.String()
+	}
+
+	return metadata
+}
+
+// clean collapses whitespace of text, drops invalid utf-8 and cuts text to limit of characters.
+func clean(text string, limit int) string {
+	text = strings.Join(strings.Fields(strings.ToValidUTF8(text, "")), " ")
+
+	if utf8.RuneCountInString(text) > limit {
+		text = string([]rune(text)[:limit])
+	}
+
+	return text
+}
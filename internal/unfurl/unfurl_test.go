@@ -0,0 +1,151 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "::1"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "64:ff9b::a00:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.public, IsPublic(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<!DOCTYPE html><html><head>
+<title>
+  Pricing &amp; plans
+</title>
+<meta name="description" content="Compare   our plans">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body><title>Not a title</title></body></html>`))
+		case "/moved":
+			http.Redirect(w, r, "/docs/og", http.StatusFound)
+		case "/docs/og":
+			w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+			w.Write([]byte("<html><head><meta property=\"og:title\" content=\"\xcf\xf0\xe8\xe2\xe5\xf2\"><meta property=\"og:description\" content=\"Open Graph\"></head></html>"))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + "<title>Too far</title></head></html>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher := MakeFetcher(100*time.Millisecond, 4096, 2, 0, true)
+
+	metadata, err := fetcher.Fetch(context.Background(), ts.URL+"/article")
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Title: "Pricing & plans", Description: "Compare our plans", Favicon: ts.URL + "/static/icon.png"}, metadata)
+
+	metadata, err = fetcher.Fetch(context.Background(), ts.URL+"/moved")
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Title: "Привет", Description: "Open Graph", Favicon: ts.URL + "/favicon.ico"}, metadata)
+
+	metadata, err = fetcher.Fetch(context.Background(), ts.URL+"/huge")
+	require.NoError(t, err)
+	assert.Empty(t, metadata.Title)
+
+	_, err = fetcher.Fetch(context.Background(), ts.URL+"/image")
+	assert.ErrorIs(t, err, ErrorNotHTML)
+
+	for _, path := range []string{"/missing", "/slow"} {
+		_, err = fetcher.Fetch(context.Background(), ts.URL+path)
+		assert.Error(t, err, path)
+	}
+
+	_, err = MakeFetcher(time.Second, 4096, 1, 0, false).Fetch(context.Background(), ts.URL+"/article")
+	assert.True(t, errors.Is(err, ErrorForbiddenAddress), err)
+
+	_, err = MakeFetcher(time.Second, 4096, 1, 0, false).Fetch(context.Background(), "http://localhost:1/")
+	assert.True(t, errors.Is(err, ErrorForbiddenAddress), err)
+}
+
+func TestFetcherQueue(t *testing.T) {
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocked" {
+			close(blocked)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Page " + r.URL.Path + "</title></head></html>"))
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	fetcher := MakeFetcher(time.Second, 4096, 1, 2, true)
+
+	results := make(chan error, 3)
+	done := func(ctx context.Context, metadata Metadata, err error) {
+		results <- err
+	}
+
+	// Without workers queue is filled, extra fetches are dropped instead of waiting.
+	require.True(t, fetcher.Enqueue(ts.URL+"/first", done))
+	require.True(t, fetcher.Enqueue(ts.URL+"/blocked", done))
+	assert.False(t, fetcher.Enqueue(ts.URL+"/dropped", done))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		fetcher.Run(ctx)
+		close(stopped)
+	}()
+
+	require.NoError(t, <-results)
+	<-blocked
+
+	// Fetch in progress is cancelled with context and workers are stopped.
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("workers aren't stopped")
+	}
+	assert.ErrorIs(t, <-results, context.Canceled)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,140 @@
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrorInvalidURL is error which returning when url can't be shortened, details are wrapped around it.
+var ErrorInvalidURL = errors.New("invalid url")
+
+// defaultSchemes are allowed when config.AppConfig.URLAllowedSchemes is empty.
+var defaultSchemes = []string{"http", "https"}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// Normalize validates raw url and returns its normal form, which is used for storing and duplicate detection.
+// Scheme and host are lowercased, international host is converted to punycode, default port and fragment are stripped
+// according to config.AppConfig settings.
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	if len(raw) == 0 {
+		return "", fmt.Errorf("%w: url is empty", ErrorInvalidURL)
+	}
+
+	if config.AppConfig.URLMaxLength > 0 && len(raw) > config.AppConfig.URLMaxLength {
+		return "", fmt.Errorf("%w: url is longer than %d characters", ErrorInvalidURL, config.AppConfig.URLMaxLength)
+	}
+
+	if strings.IndexFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", fmt.Errorf("%w: url contains whitespace or control characters", ErrorInvalidURL)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrorInvalidURL, unwrapParseError(err))
+	}
+
+	if len(u.Scheme) == 0 {
+		return "", fmt.Errorf("%w: url must be absolute and start with scheme", ErrorInvalidURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !isSchemeAllowed(u.Scheme) {
+		return "", fmt.Errorf("%w: scheme %q is not allowed", ErrorInvalidURL, u.Scheme)
+	}
+
+	if len(u.Opaque) > 0 || len(u.Host) == 0 {
+		return "", fmt.Errorf("%w: url must contain host", ErrorInvalidURL)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if len(port) > 0 {
+		number, err := strconv.Atoi(port)
+		if err != nil || number < 1 || number > 65535 {
+			return "", fmt.Errorf("%w: port %q is out of range", ErrorInvalidURL, port)
+		}
+
+		if config.AppConfig.URLStripDefaultPort && defaultPorts[u.Scheme] == port {
+			port = ""
+		}
+	}
+
+	if len(port) > 0 {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if len(u.Path) == 0 {
+		u.Path = "/"
+	}
+
+	if config.AppConfig.URLStripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	return u.String(), nil
+}
+
+func normalizeHost(host string) (string, error) {
+	if len(host) == 0 {
+		return "", fmt.Errorf("%w: url must contain host", ErrorInvalidURL)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil {
+		return "", fmt.Errorf("%w: host %q is not valid: %s", ErrorInvalidURL, host, err)
+	}
+
+	return strings.ToLower(ascii), nil
+}
+
+func isSchemeAllowed(scheme string) bool {
+	schemes := config.AppConfig.URLAllowedSchemes
+	if len(schemes) == 0 {
+		schemes = defaultSchemes
+	}
+
+	for _, allowed := range schemes {
+		if strings.EqualFold(strings.TrimSpace(allowed), scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unwrapParseError(err error) string {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return urlError.Err.Error()
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,63 @@
+package urlnorm
+
+import (
+	"errors"
+	"github.com/LorezV/url-shorter.git/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	config.AppConfig.URLStripDefaultPort = true
+	config.AppConfig.URLMaxLength = 64
+	defer func() {
+		config.AppConfig.URLStripDefaultPort = false
+		config.AppConfig.URLMaxLength = 0
+	}()
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "Surrounding whitespace is trimmed.", raw: "  https://practicum.yandex.ru/learn  ", want: "https://practicum.yandex.ru/learn"},
+		{name: "Scheme and host are lowercased.", raw: "HTTPS://Practicum.Yandex.RU/Learn", want: "https://practicum.yandex.ru/Learn"},
+		{name: "Empty path becomes slash.", raw: "https://yandex.ru", want: "https://yandex.ru/"},
+		{name: "Default port is stripped.", raw: "http://yandex.ru:80/a?b=c", want: "http://yandex.ru/a?b=c"},
+		{name: "Custom port is kept.", raw: "http://yandex.ru:8080/", want: "http://yandex.ru:8080/"},
+		{name: "International host is converted to punycode.", raw: "https://пример.рф/путь", want: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "IPv6 host is kept in brackets.", raw: "http://[::1]:443/", want: "http://[::1]:443/"},
+		{name: "Fragment is kept by default.", raw: "https://yandex.ru/#top", want: "https://yandex.ru/#top"},
+		{name: "Empty url.", raw: "   ", wantErr: true},
+		{name: "Url without scheme.", raw: "google.com", wantErr: true},
+		{name: "Relative path.", raw: "/admin", wantErr: true},
+		{name: "Javascript scheme.", raw: "javascript:alert(1)", wantErr: true},
+		{name: "Whitespace inside.", raw: "https://yandex.ru/a b", wantErr: true},
+		{name: "Port out of range.", raw: "https://yandex.ru:70000/", wantErr: true},
+		{name: "Too long url.", raw: "https://yandex.ru/" + string(make([]byte, 64)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrorInvalidURL))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeStripFragment(t *testing.T) {
+	config.AppConfig.URLStripFragment = true
+	defer func() { config.AppConfig.URLStripFragment = false }()
+
+	got, err := Normalize("https://yandex.ru/page#section")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://yandex.ru/page", got)
+}
@@ -0,0 +1,87 @@
+package useragent
+
+import "strings"
+
+// Operating systems recognized by Parse.
+const (
+	OSiOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSOther   = "other"
+)
+
+// Device classes recognized by Parse.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+var operatingSystems = map[string]bool{OSiOS: true, OSAndroid: true, OSWindows: true, OSMacOS: true, OSLinux: true, OSOther: true}
+
+var devices = map[string]bool{DeviceMobile: true, DeviceTablet: true, DeviceDesktop: true}
+
+// botMarkers are substrings of lowercased User-Agent of crawlers, link previewers and http libraries.
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly", "preview", "headless",
+	"curl/", "wget/", "python-requests", "go-http-client", "okhttp", "java/", "libwww",
+}
+
+// Agent is client description parsed from User-Agent header.
+type Agent struct {
+	OS     string `json:"os"`
+	Device string `json:"device"`
+	Bot    bool   `json:"bot"`
+}
+
+// Parse recognizes operating system, device class and automated clients by User-Agent header value.
+// Unknown values are reported as OSOther desktop.
+func Parse(header string) Agent {
+	ua := strings.ToLower(header)
+	agent := Agent{OS: OSOther, Device: DeviceDesktop}
+
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		agent.OS, agent.Device = OSiOS, DeviceMobile
+	case strings.Contains(ua, "ipad"):
+		agent.OS, agent.Device = OSiOS, DeviceTablet
+	case strings.Contains(ua, "android"):
+		agent.OS, agent.Device = OSAndroid, DeviceTablet
+		if strings.Contains(ua, "mobile") {
+			agent.Device = DeviceMobile
+		}
+	case strings.Contains(ua, "windows phone"):
+		agent.OS, agent.Device = OSWindows, DeviceMobile
+	case strings.Contains(ua, "windows"):
+		agent.OS = OSWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		agent.OS = OSMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"), strings.Contains(ua, "cros "):
+		agent.OS = OSLinux
+	}
+
+	if agent.Device == DeviceDesktop && strings.Contains(ua, "tablet") {
+		agent.Device = DeviceTablet
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			agent.Bot = true
+			break
+		}
+	}
+
+	return agent
+}
+
+// IsOS reports whether value is operating system recognized by Parse.
+func IsOS(value string) bool {
+	return operatingSystems[value]
+}
+
+// IsDevice reports whether value is device class recognized by Parse.
+func IsDevice(value string) bool {
+	return devices[value]
+}
@@ -0,0 +1,72 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Agent
+	}{
+		{
+			name:   "iPhone Safari.",
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			want:   Agent{OS: OSiOS, Device: DeviceMobile},
+		},
+		{
+			name:   "iPad Safari.",
+			header: "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want:   Agent{OS: OSiOS, Device: DeviceTablet},
+		},
+		{
+			name:   "Android phone Chrome.",
+			header: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			want:   Agent{OS: OSAndroid, Device: DeviceMobile},
+		},
+		{
+			name:   "Android tablet Chrome.",
+			header: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:   Agent{OS: OSAndroid, Device: DeviceTablet},
+		},
+		{
+			name:   "Windows Edge.",
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			want:   Agent{OS: OSWindows, Device: DeviceDesktop},
+		},
+		{
+			name:   "macOS Firefox.",
+			header: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.1; rv:120.0) Gecko/20100101 Firefox/120.0",
+			want:   Agent{OS: OSMacOS, Device: DeviceDesktop},
+		},
+		{
+			name:   "Linux Firefox.",
+			header: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+			want:   Agent{OS: OSLinux, Device: DeviceDesktop},
+		},
+		{
+			name:   "Googlebot smartphone.",
+			header: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:   Agent{OS: OSAndroid, Device: DeviceMobile, Bot: true},
+		},
+		{
+			name:   "Curl.",
+			header: "curl/8.4.0",
+			want:   Agent{OS: OSOther, Device: DeviceDesktop, Bot: true},
+		},
+		{
+			name:   "Empty header.",
+			header: "",
+			want:   Agent{OS: OSOther, Device: DeviceDesktop},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.header))
+		})
+	}
+}
@@ -45,9 +45,30 @@ func (w GzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush writes buffered compressed data and flushes response.
+func (w GzipWriter) Flush() {
+	if flusher, ok := w.Writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns original ResponseWriter.
+func (w GzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // EncodeUserID encode string with user id by secret key from config.
 func EncodeUserID(id string) []byte {
+	return Sign(id)
+}
+
+// Sign returns HMAC-SHA256 of value by secret key from config.
+func Sign(value string) []byte {
 	h := hmac.New(sha256.New, []byte(config.AppConfig.SecretKey))
-	h.Write([]byte(id))
+	h.Write([]byte(value))
 	return h.Sum(nil)
 }